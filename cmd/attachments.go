@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func init() {
+	rootCmd.AddCommand(attachmentCmd)
+	attachmentCmd.AddCommand(attachmentLsCmd)
+	attachmentCmd.AddCommand(attachmentGetCmd)
+	attachmentCmd.AddCommand(attachmentPutCmd)
+	attachmentCmd.AddCommand(attachmentRmCmd)
+	attachmentGetCmd.Flags().StringP("output", "o", "", "File to write the attachment to (created 0600, default: stdout)")
+	attachmentPutCmd.Flags().StringP("name", "n", "", "Attachment name (default: the file's base name)")
+}
+
+var attachmentCmd = &cobra.Command{
+	Use:   "attachment",
+	Short: "List, extract and add entry attachments",
+	Long: `List, extract and add the attachments (binaries) of entries in the external databases.
+
+Entries:
+  DATABASE/[GROUP/...]TITLE   Entry in a registered database
+  fav:INDEX                   Favorite by index, following it to its source entry
+
+Examples:
+  gokp attachment ls prod/servers/bastion
+  gokp attachment get prod/servers/bastion id_ed25519 -o ~/.ssh/id_ed25519
+  gokp attachment put fav:2 ./license.pdf
+  gokp attachment rm fav:2 license.pdf`,
+}
+
+var attachmentLsCmd = &cobra.Command{
+	Use:   "ls ENTRY",
+	Short: "List the attachments of an entry",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, db, entry, err := openAttachmentEntry(args[0])
+		if err != nil {
+			return err
+		}
+
+		if len(entry.Binaries) == 0 {
+			fmt.Printf("Entry '%s' has no attachments.\n", entry.GetTitle())
+			return nil
+		}
+		for _, ref := range entry.Binaries {
+			size := "missing"
+			if content, err := readEntryAttachment(db, entry, ref.Name); err == nil {
+				size = fmt.Sprintf("%d bytes", len(content))
+			}
+			fmt.Printf("%s (%s)\n", ref.Name, size)
+		}
+		return nil
+	},
+}
+
+var attachmentGetCmd = &cobra.Command{
+	Use:   "get ENTRY NAME",
+	Short: "Write an attachment to a file or stdout",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+
+		_, db, entry, err := openAttachmentEntry(args[0])
+		if err != nil {
+			return err
+		}
+
+		content, err := readEntryAttachment(db, entry, args[1])
+		if err != nil {
+			return fmt.Errorf("failed to read attachment: %w", err)
+		}
+
+		if output == "" {
+			if _, err := os.Stdout.Write(content); err != nil {
+				return fmt.Errorf("error writing attachment: %w", err)
+			}
+			return nil
+		}
+		if err := writeSecretFile(output, content); err != nil {
+			return fmt.Errorf("error writing attachment: %w", err)
+		}
+		fmt.Printf("Saved '%s' to %s\n", args[1], output)
+		return nil
+	},
+}
+
+var attachmentPutCmd = &cobra.Command{
+	Use:   "put ENTRY FILE",
+	Short: "Attach a file to an entry, replacing an attachment of the same name",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			name = filepath.Base(args[1])
+		}
+
+		content, err := os.ReadFile(args[1])
+		if err != nil {
+			return fmt.Errorf("error reading file: %w", err)
+		}
+
+		dbPath, db, entry, err := openAttachmentEntry(args[0])
+		if err != nil {
+			return err
+		}
+
+		pushEntryHistory(db, entry)
+		replaced := removeEntryAttachment(entry, name)
+		binary := db.AddBinary(content)
+		entry.Binaries = append(entry.Binaries, binary.CreateReference(name))
+		touchEntry(entry)
+
+		if err := saveExternalKeepassDB(db, dbPath); err != nil {
+			return fmt.Errorf("failed to save database: %w", err)
+		}
+		if replaced {
+			fmt.Printf("Replaced attachment '%s' on '%s'\n", name, entry.GetTitle())
+		} else {
+			fmt.Printf("Added attachment '%s' to '%s'\n", name, entry.GetTitle())
+		}
+		return nil
+	},
+}
+
+var attachmentRmCmd = &cobra.Command{
+	Use:   "rm ENTRY NAME",
+	Short: "Remove an attachment from an entry",
+	Long: `Remove an attachment from an entry.
+
+The previous version of the entry is kept in its history, so the attachment data stays
+in the database until that history version is deleted, e.g. by KeePassXC's history
+size limits or maintenance.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbPath, db, entry, err := openAttachmentEntry(args[0])
+		if err != nil {
+			return err
+		}
+
+		pushEntryHistory(db, entry)
+		if !removeEntryAttachment(entry, args[1]) {
+			return newError(ErrNotFound, "entry '%s' has no attachment '%s'", entry.GetTitle(), args[1])
+		}
+		touchEntry(entry)
+
+		// The history version pushed above still references the binary, so its data stays
+		// in the file
+		if err := saveExternalKeepassDB(db, dbPath); err != nil {
+			return fmt.Errorf("failed to save database: %w", err)
+		}
+		fmt.Printf("Removed attachment '%s' from '%s'\n", args[1], entry.GetTitle())
+		return nil
+	},
+}
+
+// openAttachmentEntry resolves an entry reference, returning the path of its database too
+func openAttachmentEntry(ref string) (string, *gokeepasslib.Database, *gokeepasslib.Entry, error) {
+	_, _, gokpKDBX := pathSelection(false)
+
+	secret, err := getGoKPPassword(gokpKDBX)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to get GoKP password: %w", err)
+	}
+
+	gokpDB, err := openKeepassDB(gokpKDBX, secret)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to open GoKP database: %w", err)
+	}
+
+	resolver := newSecretResolver(gokpDB)
+	db, entry, err := resolver.resolveEntry(ref)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to find entry: %w", err)
+	}
+	dbPath, err := resolver.databasePath(db)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to find database path: %w", err)
+	}
+	return dbPath, db, entry, nil
+}
+
+// readEntryAttachment returns the decoded content of the named attachment of an entry.
+// FindBinary looks in the meta binaries for KDBX 3.1 and the inner header for KDBX 4.
+func readEntryAttachment(db *gokeepasslib.Database, entry *gokeepasslib.Entry, name string) ([]byte, error) {
+	for _, ref := range entry.Binaries {
+		if ref.Name != name {
+			continue
+		}
+		binary := db.FindBinary(ref.Value.ID)
+		if binary == nil {
+			return nil, fmt.Errorf("attachment '%s' references missing binary %d", name, ref.Value.ID)
+		}
+		return binary.GetContentBytes()
+	}
+	return nil, newError(ErrNotFound, "entry '%s' has no attachment '%s'", entry.GetTitle(), name)
+}
+
+// removeEntryAttachment drops the named attachment reference, reporting whether it existed
+func removeEntryAttachment(entry *gokeepasslib.Entry, name string) bool {
+	for i, ref := range entry.Binaries {
+		if ref.Name == name {
+			entry.Binaries = append(entry.Binaries[:i], entry.Binaries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentNames lists the names of an entry's attachments
+func attachmentNames(entry gokeepasslib.Entry) []string {
+	names := make([]string, 0, len(entry.Binaries))
+	for _, ref := range entry.Binaries {
+		names = append(names, ref.Name)
+	}
+	return names
+}
+
+// touchEntry sets an entry's last modification time to now, keeping the time format
+// of the database it belongs to
+func touchEntry(entry *gokeepasslib.Entry) {
+	if entry.Times.LastModificationTime == nil {
+		return
+	}
+	entry.Times.LastModificationTime.Time = time.Now().UTC()
+}
@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.Flags().StringP("database", "d", "", "Audit only a specific external database")
+	auditCmd.RegisterFlagCompletionFunc("database", completeDatabaseNames)
+	auditCmd.Flags().Float64("min-entropy", 60, "Report passwords with an estimated entropy below this many bits")
+	auditCmd.Flags().Int("max-age", 365, "Report passwords unchanged for more than this many days (0 to disable)")
+	auditCmd.Flags().Bool("json", false, "Print the report as JSON")
+	auditCmd.Flags().String("breach-db", "", "Check passwords against a local Have I Been Pwned SHA-1 list or range files")
+}
+
+// Audit checks, as reported in findings and the summary
+const (
+	auditWeak       = "weak"
+	auditReused     = "reused"
+	auditExpired    = "expired"
+	auditOld        = "old"
+	auditNoUserName = "no-username"
+	auditNoURL      = "no-url"
+	auditBreached   = "breached"
+)
+
+var auditChecks = []string{auditWeak, auditReused, auditExpired, auditOld, auditNoUserName, auditNoURL}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Report weak, reused, expired and old passwords in the external databases",
+	Long: `Check every entry of the external databases referenced in the GoKP database and report:
+
+  weak         passwords with an estimated entropy below --min-entropy bits
+  reused       passwords shared by several entries, across all databases
+  expired      entries past their expiry time
+  old          passwords unchanged for more than --max-age days, based on the entry history
+  no-username  entries without a username
+  no-url       entries without a URL
+  breached     passwords found in a local Have I Been Pwned list, with --breach-db
+
+--breach-db takes the Pwned Passwords SHA-1 list sorted by hash ("HASH:COUNT" lines,
+searched with a binary search), a folder of range files named PREFIX.txt as written by
+the Pwned Passwords downloader, or a single range file. Nothing is sent over the network.
+
+Entries in the recycle bin are skipped. The report ends with a summary table per
+database; --json prints the whole report as JSON instead.
+
+Examples:
+  gokp audit
+  gokp audit -d work --max-age 180
+  gokp audit --json > audit-$(date +%F).json
+  gokp audit --breach-db ~/pwned-passwords-sha1-ordered-by-hash-v8.txt`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targetDatabase, _ := cmd.Flags().GetString("database")
+		minEntropy, _ := cmd.Flags().GetFloat64("min-entropy")
+		maxAge, _ := cmd.Flags().GetInt("max-age")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		breachPath, _ := cmd.Flags().GetString("breach-db")
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		auditor := &passwordAuditor{
+			minEntropy: minEntropy,
+			maxAge:     time.Duration(maxAge) * 24 * time.Hour,
+			now:        time.Now(),
+			passwords:  map[string][]auditFinding{},
+			report: &auditReport{
+				Generated:  time.Now(),
+				MinEntropy: minEntropy,
+				MaxAgeDays: maxAge,
+				Checks:     append([]string{}, auditChecks...),
+				Findings:   []auditFinding{},
+			},
+		}
+		if breachPath != "" {
+			breaches, err := openBreachDB(breachPath)
+			if err != nil {
+				return fmt.Errorf("failed to open breach list: %w", err)
+			}
+			defer breaches.close()
+			auditor.breaches = breaches
+			auditor.report.Checks = append(auditor.report.Checks, auditBreached)
+			auditor.report.BreachDB = breaches.String()
+		}
+
+		// Warnings go to stderr so --json output stays parseable
+		databases := openExternalDatabases(gokpDB, targetDatabase, os.Stderr)
+		if len(databases) == 0 {
+			return newError(ErrNoDatabases, "no accessible external databases found")
+		}
+		for _, external := range databases {
+			auditor.auditDatabase(external)
+		}
+		auditor.findReused()
+		if auditor.breaches != nil {
+			if err := auditor.findBreached(); err != nil {
+				return fmt.Errorf("failed to check breach list: %w", err)
+			}
+		}
+
+		if asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(auditor.report); err != nil {
+				return fmt.Errorf("failed to write report: %w", err)
+			}
+			return nil
+		}
+		printAuditReport(auditor.report)
+		return nil
+	},
+}
+
+type auditReport struct {
+	Generated  time.Time      `json:"generated"`
+	MinEntropy float64        `json:"min_entropy"`
+	MaxAgeDays int            `json:"max_age_days"`
+	BreachDB   string         `json:"breach_db,omitempty"`
+	Checks     []string       `json:"checks"`
+	Summary    []auditSummary `json:"summary"`
+	Findings   []auditFinding `json:"findings"`
+}
+
+type auditSummary struct {
+	Database string         `json:"database"`
+	Entries  int            `json:"entries"`
+	Counts   map[string]int `json:"counts"`
+}
+
+type auditFinding struct {
+	Check    string `json:"check"`
+	Database string `json:"database"`
+	UUID     string `json:"uuid"`
+	Title    string `json:"title"`
+	Group    string `json:"group"`
+	Detail   string `json:"detail"`
+}
+
+type passwordAuditor struct {
+	minEntropy float64
+	maxAge     time.Duration
+	now        time.Time
+	report     *auditReport
+	breaches   *breachDB
+
+	// passwords maps each password to the entries using it, for the reuse check
+	passwords map[string][]auditFinding
+}
+
+func (a *passwordAuditor) auditDatabase(external ExternalDatabase) {
+	summary := auditSummary{Database: external.Name, Counts: map[string]int{}}
+	for _, check := range a.report.Checks {
+		summary.Counts[check] = 0
+	}
+	a.report.Summary = append(a.report.Summary, summary)
+
+	forEachActiveEntry(external.DB, func(groupPath []string, entry *gokeepasslib.Entry) {
+		a.report.Summary[len(a.report.Summary)-1].Entries++
+		base := auditFinding{
+			Database: external.Name,
+			UUID:     fmt.Sprintf("%x", entry.UUID),
+			Title:    entry.GetTitle(),
+			Group:    strings.Join(groupPath, "/"),
+		}
+		// Score the password the entry actually uses, not a reference to another entry's
+		password := getEntryValue(resolvedEntry(external.DB, *entry), "Password")
+
+		if password == "" {
+			a.add(base, auditWeak, "empty password")
+		} else if bits := passwordEntropy(password); bits < a.minEntropy {
+			a.add(base, auditWeak, fmt.Sprintf("estimated entropy %.0f bits", bits))
+		}
+		if password != "" {
+			a.passwords[password] = append(a.passwords[password], base)
+		}
+		if entry.Times.Expires.Bool && entry.Times.ExpiryTime != nil && entry.Times.ExpiryTime.Time.Before(a.now) {
+			a.add(base, auditExpired, "expired "+entry.Times.ExpiryTime.Time.Local().Format("2006-01-02"))
+		}
+		if changed := passwordChangedAt(entry); a.maxAge > 0 && !changed.IsZero() && a.now.Sub(changed) > a.maxAge {
+			a.add(base, auditOld, fmt.Sprintf("unchanged for %d days", int(a.now.Sub(changed).Hours()/24)))
+		}
+		if getEntryValue(*entry, "UserName") == "" {
+			a.add(base, auditNoUserName, "no username")
+		}
+		if getEntryValue(*entry, "URL") == "" {
+			a.add(base, auditNoURL, "no URL")
+		}
+	})
+}
+
+// findReused reports every entry whose password is used by another entry
+func (a *passwordAuditor) findReused() {
+	for _, password := range sortedKeys(a.passwords) {
+		users := a.passwords[password]
+		if len(users) < 2 {
+			continue
+		}
+		for i, user := range users {
+			var others []string
+			for j, other := range users {
+				if i != j {
+					others = append(others, other.Database+"/"+other.Title)
+				}
+			}
+			a.add(user, auditReused, "also used by "+strings.Join(others, ", "))
+		}
+	}
+}
+
+// findBreached reports every entry whose password is in the breach list. Each distinct
+// password is hashed and looked up once.
+func (a *passwordAuditor) findBreached() error {
+	for _, password := range sortedKeys(a.passwords) {
+		count, err := a.breaches.passwordBreachCount(password)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			continue
+		}
+		for _, user := range a.passwords[password] {
+			a.add(user, auditBreached, fmt.Sprintf("seen %d time(s) in breaches", count))
+		}
+	}
+	return nil
+}
+
+func (a *passwordAuditor) add(base auditFinding, check, detail string) {
+	base.Check = check
+	base.Detail = detail
+	a.report.Findings = append(a.report.Findings, base)
+	for i := range a.report.Summary {
+		if a.report.Summary[i].Database == base.Database {
+			a.report.Summary[i].Counts[check]++
+		}
+	}
+}
+
+// sortedKeys returns the passwords in order, so reports are stable between runs
+func sortedKeys(passwords map[string][]auditFinding) []string {
+	keys := make([]string, 0, len(passwords))
+	for password := range passwords {
+		keys = append(keys, password)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printAuditReport(report *auditReport) {
+	for _, check := range report.Checks {
+		first := true
+		for _, finding := range report.Findings {
+			if finding.Check != check {
+				continue
+			}
+			if first {
+				fmt.Printf("\n%s%s%s\n", ColorBoldCyan, check, ColorReset)
+				first = false
+			}
+			fmt.Printf("  %s/%s (%s): %s\n", finding.Database, finding.Title, finding.UUID, finding.Detail)
+		}
+	}
+
+	fmt.Println()
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "DATABASE\tENTRIES\t%s\n", strings.ToUpper(strings.Join(report.Checks, "\t")))
+	for _, summary := range report.Summary {
+		fmt.Fprintf(table, "%s\t%d", summary.Database, summary.Entries)
+		for _, check := range report.Checks {
+			fmt.Fprintf(table, "\t%d", summary.Counts[check])
+		}
+		fmt.Fprintln(table)
+	}
+	table.Flush()
+}
+
+// forEachActiveEntry calls fn for every entry of a database outside its recycle bin
+func forEachActiveEntry(db *gokeepasslib.Database, fn func(groupPath []string, entry *gokeepasslib.Entry)) {
+	var recycleBin gokeepasslib.UUID
+	if db.Content.Meta != nil && db.Content.Meta.RecycleBinEnabled.Bool {
+		recycleBin = db.Content.Meta.RecycleBinUUID
+	}
+
+	var walk func(groups []gokeepasslib.Group, path []string)
+	walk = func(groups []gokeepasslib.Group, path []string) {
+		for i := range groups {
+			if recycleBin != (gokeepasslib.UUID{}) && groups[i].UUID.Compare(recycleBin) {
+				continue
+			}
+			groupPath := append(append([]string{}, path...), groups[i].Name)
+			for j := range groups[i].Entries {
+				fn(groupPath, &groups[i].Entries[j])
+			}
+			walk(groups[i].Groups, groupPath)
+		}
+	}
+	walk(db.Content.Root.Groups, nil)
+}
+
+// passwordEntropy estimates the entropy of a password in bits from the size of the
+// character classes it uses and its length. Runs of the same character count once, so
+// "aaaaaaaa" scores like "a".
+func passwordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	length := 0
+	var previous rune = -1
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r) && r < unicode.MaxASCII:
+			lower = true
+		case unicode.IsUpper(r) && r < unicode.MaxASCII:
+			upper = true
+		case unicode.IsDigit(r) && r < unicode.MaxASCII:
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+		if r != previous {
+			length++
+		}
+		previous = r
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(length) * math.Log2(float64(pool))
+}
+
+// passwordChangedAt returns when the current password was set, walking back through the
+// entry history while the password stays the same
+func passwordChangedAt(entry *gokeepasslib.Entry) time.Time {
+	versions := entryVersions(entry)
+	changed := timeOf(versions[0].Times.LastModificationTime)
+	for _, version := range versions[1:] {
+		if version.GetPassword() != entry.GetPassword() {
+			break
+		}
+		changed = timeOf(version.Times.LastModificationTime)
+	}
+	return changed
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestAuditScoresReferencedPasswords(t *testing.T) {
+	strong := newTestEntry("Title", "strong", "Password", "correct-horse-battery-staple-42")
+	weak := newTestEntry("Title", "weak", "Password", "abc")
+	copiesStrong := newTestEntry("Title", "copies-strong", "Password", fmt.Sprintf("{REF:P@I:%x}", strong.UUID))
+	copiesWeak := newTestEntry("Title", "copies-weak", "Password", fmt.Sprintf("{REF:P@I:%x}", weak.UUID))
+	db := newTestDatabase("password", newTestGroup("Root", strong, weak, copiesStrong, copiesWeak))
+
+	auditor := &passwordAuditor{
+		minEntropy: 60,
+		now:        time.Now(),
+		passwords:  map[string][]auditFinding{},
+		report:     &auditReport{Checks: append([]string{}, auditChecks...)},
+	}
+	auditor.auditDatabase(ExternalDatabase{Name: "prod", DB: db})
+	auditor.findReused()
+
+	got := map[string][]string{}
+	for _, finding := range auditor.report.Findings {
+		got[finding.Check] = append(got[finding.Check], finding.Title)
+	}
+	want := map[string][]string{
+		auditWeak:   {"copies-weak", "weak"},
+		auditReused: {"copies-strong", "copies-weak", "strong", "weak"},
+	}
+	for check, titles := range want {
+		sort.Strings(got[check])
+		if fmt.Sprint(got[check]) != fmt.Sprint(titles) {
+			t.Errorf("%s findings = %q, want %q", check, got[check], titles)
+		}
+	}
+}
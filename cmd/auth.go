@@ -2,8 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
-	"os"
 	"syscall"
 
 	"github.com/spf13/cobra"
@@ -25,35 +23,47 @@ var authCmd = &cobra.Command{
 var logoutCmd = &cobra.Command{
 	Use:   "logout",
 	Short: "Remove gokp password from OS keystore",
-	Run: func(cmd *cobra.Command, args []string) {
-		delete_password("gokp", "local")
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := delete_password("gokp", "local"); err != nil {
+			return fmt.Errorf("failed to remove password from keystore: %w", err)
+		}
 		println("gokp password cleared.")
+		return nil
 	},
 }
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Save gokp password to OS keystore",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Print("Enter admin password: ")
-		password, _ := term.ReadPassword(int(syscall.Stdin))
-		if string(password) == "" {
-			fmt.Println("\nPassword is required")
-			os.Exit(1)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		passwordStr, supplied, err := passwordFromInput()
+		if err != nil {
+			return err
+		}
+		if !supplied {
+			if err := requireInput("The admin password"); err != nil {
+				return err
+			}
+			fmt.Print("Enter admin password: ")
+			password, _ := term.ReadPassword(int(syscall.Stdin))
+			if string(password) == "" {
+				fmt.Println()
+				return newError(ErrUsage, "password is required")
+			}
+			fmt.Println()
+			passwordStr = string(password)
 		}
-		fmt.Println()
-		passwordStr := string(password)
 
-		save_password("gokp", "local", passwordStr)
+		if err := save_password("gokp", "local", passwordStr); err != nil {
+			return fmt.Errorf("failed to save password to keystore: %w", err)
+		}
 		println("Saved gokp password to keystore")
+		return nil
 	},
 }
 
-func save_password(service string, user string, password string) {
-	err := keyring.Set(service, user, password)
-	if err != nil {
-		log.Fatal(err)
-	}
+func save_password(service string, user string, password string) error {
+	return keyring.Set(service, user, password)
 }
 
 func get_password(service string, user string) (string, error) {
@@ -61,9 +71,6 @@ func get_password(service string, user string) (string, error) {
 	return secret, err
 }
 
-func delete_password(service string, user string) {
-	err := keyring.Delete(service, user)
-	if err != nil {
-		log.Fatal((err))
-	}
+func delete_password(service string, user string) error {
+	return keyring.Delete(service, user)
 }
@@ -0,0 +1,444 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func init() {
+	rootCmd.AddCommand(typeCmd)
+	typeCmd.Flags().StringP("sequence", "s", "", "Keystroke sequence to type instead of the entry's Auto-Type sequence")
+	typeCmd.Flags().Duration("wait", 2*time.Second, "Time to focus the target window before typing starts")
+	typeCmd.Flags().String("backend", "auto", "Tool that sends the keystrokes: auto, xdotool (X11) or ydotool (uinput)")
+	typeCmd.Flags().BoolP("dry-run", "n", false, "Print the keystrokes instead of typing them, masking passwords")
+}
+
+// defaultAutoTypeSequence is KeePass's sequence for entries and groups that set none
+const defaultAutoTypeSequence = "{USERNAME}{TAB}{PASSWORD}{ENTER}"
+
+var typeCmd = &cobra.Command{
+	Use:   "type ENTRY",
+	Short: "Type an entry's Auto-Type sequence into the focused window",
+	Long: `Type the KeePass Auto-Type keystroke sequence of an entry into the focused window,
+using xdotool on X11 or ydotool (uinput) elsewhere.
+
+The sequence is the one given with --sequence, else a window association of the entry
+matching the focused window's title (xdotool only), else the entry's default sequence,
+else the nearest group's, else {USERNAME}{TAB}{PASSWORD}{ENTER}.
+
+Sequences follow the KeePass grammar:
+  {USERNAME} {PASSWORD} {TITLE} {URL} {NOTES} {S:Field} {REF:...}   entry fields
+  {TAB} {ENTER} {SPACE} {BS} {DEL} {UP} {F1}... {WIN}               special keys
+  {TAB 3}                                                         repeated keys
+  {DELAY 500} {DELAY=50}                                          pause, keystroke delay (ms)
+  {CLEARFIELD}                                                    select all and delete
+  + ^ % ~                                                         shift, ctrl, alt, enter
+  ^(ac)  {+} {^} {%} {~} {(} {)} {{} {}}                          groups and literals
+
+Entries:
+  DATABASE/[GROUP/...]TITLE   Entry in a registered database
+  fav:INDEX                   Favorite by index, following it to its source entry
+  QUERY                       Search query that matches a single entry
+
+Examples:
+  gokp type fav:2
+  gokp type prod/servers/bastion --wait 5s
+  gokp type gmail -s "{USERNAME}{ENTER}{DELAY 1500}{PASSWORD}{ENTER}"
+  gokp type fav:2 -n`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sequence, _ := cmd.Flags().GetString("sequence")
+		wait, _ := cmd.Flags().GetDuration("wait")
+		backend, _ := cmd.Flags().GetString("backend")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		resolver := newSecretResolver(gokpDB)
+		db, entry, err := lookupEntry(resolver, gokpKDBX, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to find entry: %w", err)
+		}
+
+		var typer keyTyper
+		if !dryRun {
+			if typer, err = newKeyTyper(backend); err != nil {
+				return fmt.Errorf("cannot type keystrokes: %w", err)
+			}
+		}
+
+		groups := entryGroupChain(db, entry)
+		if sequence == "" && !autoTypeEnabled(entry, groups) {
+			return fmt.Errorf("auto-type is disabled for entry '%s', pass --sequence to type it anyway", entry.GetTitle())
+		}
+
+		// Wait first, the window association depends on the window focused by then
+		if !dryRun && wait > 0 {
+			fmt.Printf("Typing '%s' into the focused window in %s...\n", entry.GetTitle(), wait)
+			time.Sleep(wait)
+		}
+		if sequence == "" {
+			window := ""
+			if typer != nil {
+				window = typer.activeWindow()
+			}
+			sequence = autoTypeSequence(entry, groups, window)
+		}
+
+		strokes, err := parseAutoTypeSequence(sequence, newFieldExpander(db), entry)
+		if err != nil {
+			return newError(ErrUsage, "invalid Auto-Type sequence '%s': %w", sequence, err)
+		}
+
+		if dryRun {
+			for _, stroke := range strokes {
+				fmt.Println(stroke)
+			}
+			return nil
+		}
+		if err := typeKeystrokes(typer, strokes); err != nil {
+			return fmt.Errorf("failed to type '%s': %w", entry.GetTitle(), err)
+		}
+		return nil
+	},
+}
+
+// lookupEntry finds an entry by reference like resolveEntry, or by a search query that
+// must match a single entry of the search indexes, which are saved to gokpKDBX if rebuilt
+func lookupEntry(resolver *secretResolver, gokpKDBX string, query string) (*gokeepasslib.Database, *gokeepasslib.Entry, error) {
+	if strings.HasPrefix(query, "fav:") || strings.Contains(query, "/") {
+		return resolver.resolveEntry(query)
+	}
+
+	indexes := loadSearchIndexes(resolver.gokpDB, gokpKDBX, "", os.Stderr)
+
+	type match struct {
+		database string
+		entry    indexedEntry
+	}
+	var matches, exact []match
+	for _, indexed := range indexes {
+		for _, entry := range searchIndexEntries(indexed.Index, query, false, false, "") {
+			matches = append(matches, match{indexed.Name, entry})
+			if strings.EqualFold(entry.Title, query) {
+				exact = append(exact, match{indexed.Name, entry})
+			}
+		}
+	}
+	if len(exact) == 1 {
+		matches = exact
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil, newError(ErrNotFound, "no entry matches '%s'", query)
+	case 1:
+		db, err := resolver.database(matches[0].database)
+		if err != nil {
+			return nil, nil, err
+		}
+		entry := findEntryByUUID(db, matches[0].entry.UUID)
+		if entry == nil {
+			return nil, nil, fmt.Errorf("entry '%s' no longer exists in '%s', run 'gokp manage update'", matches[0].entry.Title, matches[0].database)
+		}
+		return db, entry, nil
+	default:
+		var refs []string
+		for _, m := range matches {
+			refs = append(refs, "  "+strings.Join(append(append([]string{m.database}, m.entry.Group...), m.entry.Title), "/"))
+		}
+		return nil, nil, newError(ErrAmbiguous, "%d entries match '%s', use one of:\n%s", len(matches), query, strings.Join(refs, "\n"))
+	}
+}
+
+// entryGroupChain returns the groups holding an entry, innermost first
+func entryGroupChain(db *gokeepasslib.Database, entry *gokeepasslib.Entry) []*gokeepasslib.Group {
+	var chain []*gokeepasslib.Group
+	for group := findEntryParent(db.Content.Root.Groups, entry.UUID); group != nil; group = findParentGroup(db.Content.Root.Groups, group.UUID) {
+		chain = append(chain, group)
+	}
+	return chain
+}
+
+// autoTypeEnabled checks the entry's Auto-Type setting and the nearest group that sets one.
+// Like KeePass, an entry without an <AutoType> element has Auto-Type enabled. The decoder
+// leaves no trace of a missing element, so Auto-Type data that is entirely empty counts as
+// missing.
+func autoTypeEnabled(entry *gokeepasslib.Entry, groups []*gokeepasslib.Group) bool {
+	missing := entry.AutoType.DataTransferObfuscation == 0 && entry.AutoType.DefaultSequence == "" && len(entry.AutoType.Associations) == 0
+	if !entry.AutoType.Enabled.Bool && !missing {
+		return false
+	}
+	for _, group := range groups {
+		if group.EnableAutoType.Valid {
+			return group.EnableAutoType.Bool
+		}
+	}
+	return true
+}
+
+// autoTypeSequence picks the sequence to type into the window with the given title
+func autoTypeSequence(entry *gokeepasslib.Entry, groups []*gokeepasslib.Group, window string) string {
+	inherited := entry.AutoType.DefaultSequence
+	for _, group := range groups {
+		if inherited != "" {
+			break
+		}
+		inherited = group.DefaultAutoTypeSequence
+	}
+	if inherited == "" {
+		inherited = defaultAutoTypeSequence
+	}
+
+	if window != "" {
+		for _, association := range entry.AutoType.Associations {
+			if matchWindowTitle(association.Window, window) {
+				if association.KeystrokeSequence != "" {
+					return association.KeystrokeSequence
+				}
+				return inherited
+			}
+		}
+	}
+	return inherited
+}
+
+// matchWindowTitle matches a KeePass window pattern: case-insensitive with * wildcards,
+// or a regular expression between // delimiters
+func matchWindowTitle(pattern, title string) bool {
+	if len(pattern) > 4 && strings.HasPrefix(pattern, "//") && strings.HasSuffix(pattern, "//") {
+		re, err := regexp.Compile("(?i)" + pattern[2:len(pattern)-2])
+		return err == nil && re.MatchString(title)
+	}
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	re := regexp.MustCompile("(?is)^" + strings.Join(parts, ".*") + "$")
+	return re.MatchString(title)
+}
+
+type keystrokeKind int
+
+const (
+	strokeText keystrokeKind = iota
+	strokeKey
+	strokePause
+	strokeSetDelay
+)
+
+// keystroke is one step of a parsed Auto-Type sequence
+type keystroke struct {
+	kind keystrokeKind
+	// text to type for strokeText, a key name from autoTypeKeys or a character for strokeKey
+	text string
+	// mods holds the modifiers (shift, ctrl, alt) held down while pressing a key
+	mods []string
+	// delay is the pause for strokePause and the delay between keystrokes for strokeSetDelay
+	delay time.Duration
+	// secret marks text taken from a password or protected field
+	secret bool
+}
+
+func (k keystroke) String() string {
+	switch k.kind {
+	case strokeText:
+		if k.secret {
+			return "type ********"
+		}
+		return fmt.Sprintf("type %q", k.text)
+	case strokeKey:
+		return "key  " + strings.Join(append(append([]string{}, k.mods...), k.text), "+")
+	case strokePause:
+		return fmt.Sprintf("wait %s", k.delay)
+	default:
+		return fmt.Sprintf("set keystroke delay to %s", k.delay)
+	}
+}
+
+// autoTypeKeys maps KeePass special key names to xdotool key names
+var autoTypeKeys = map[string]string{
+	"TAB": "Tab", "ENTER": "Return", "SPACE": "space",
+	"BACKSPACE": "BackSpace", "BS": "BackSpace", "BKSP": "BackSpace",
+	"DELETE": "Delete", "DEL": "Delete", "INSERT": "Insert", "INS": "Insert",
+	"HOME": "Home", "END": "End", "PGUP": "Prior", "PGDN": "Next",
+	"UP": "Up", "DOWN": "Down", "LEFT": "Left", "RIGHT": "Right",
+	"ESC": "Escape", "CAPSLOCK": "Caps_Lock", "NUMLOCK": "Num_Lock", "SCROLLLOCK": "Scroll_Lock",
+	"PRTSC": "Print", "BREAK": "Break", "HELP": "Help",
+	"WIN": "Super_L", "LWIN": "Super_L", "RWIN": "Super_R", "APPS": "Menu",
+	"ADD": "KP_Add", "SUBTRACT": "KP_Subtract", "MULTIPLY": "KP_Multiply", "DIVIDE": "KP_Divide",
+	"NUMPAD0": "KP_0", "NUMPAD1": "KP_1", "NUMPAD2": "KP_2", "NUMPAD3": "KP_3", "NUMPAD4": "KP_4",
+	"NUMPAD5": "KP_5", "NUMPAD6": "KP_6", "NUMPAD7": "KP_7", "NUMPAD8": "KP_8", "NUMPAD9": "KP_9",
+	"F1": "F1", "F2": "F2", "F3": "F3", "F4": "F4", "F5": "F5", "F6": "F6", "F7": "F7", "F8": "F8",
+	"F9": "F9", "F10": "F10", "F11": "F11", "F12": "F12", "F13": "F13", "F14": "F14", "F15": "F15", "F16": "F16",
+}
+
+// autoTypeModifiers maps the KeePass modifier characters to modifier names
+var autoTypeModifiers = map[rune]string{'+': "shift", '^': "ctrl", '%': "alt"}
+
+// parseAutoTypeSequence turns a KeePass keystroke sequence into keystrokes, expanding the
+// entry's field placeholders with expander
+func parseAutoTypeSequence(sequence string, expander *fieldExpander, entry *gokeepasslib.Entry) ([]keystroke, error) {
+	var strokes []keystroke
+	// mods apply to the next key, groupMods to every key inside a modifier group like ^(ac)
+	var mods, groupMods []string
+	inGroup := false
+
+	addText := func(text string, secret bool) {
+		if n := len(strokes); n > 0 && strokes[n-1].kind == strokeText && strokes[n-1].secret == secret {
+			strokes[n-1].text += text
+			return
+		}
+		strokes = append(strokes, keystroke{kind: strokeText, text: text, secret: secret})
+	}
+	addKey := func(key string) {
+		held := append(append([]string{}, groupMods...), mods...)
+		strokes = append(strokes, keystroke{kind: strokeKey, text: key, mods: held})
+		mods = nil
+	}
+	addChar := func(r rune) error {
+		if len(mods) == 0 && len(groupMods) == 0 {
+			addText(string(r), false)
+			return nil
+		}
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return fmt.Errorf("modifiers can only be combined with letters, digits and special keys, not '%c'", r)
+		}
+		addKey(string(unicode.ToLower(r)))
+		return nil
+	}
+
+	runes := []rune(sequence)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '{':
+			// {}} types a closing brace, so the token ends at the first } after the first rune
+			end := -1
+			for j := i + 2; j < len(runes); j++ {
+				if runes[j] == '}' {
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '{' at position %d", i+1)
+			}
+			token := string(runes[i+1 : end])
+			i = end
+
+			if len([]rune(token)) == 1 && strings.ContainsAny(token, "+^%~(){}[]") {
+				if err := addChar([]rune(token)[0]); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := parseAutoTypeToken(token, &strokes, addKey, addText, expander, entry, len(mods) > 0); err != nil {
+				return nil, err
+			}
+		case autoTypeModifiers[r] != "":
+			mods = append(mods, autoTypeModifiers[r])
+		case r == '(' && len(mods) > 0 && !inGroup:
+			inGroup, groupMods, mods = true, mods, nil
+		case r == ')' && inGroup:
+			inGroup, groupMods = false, nil
+		case r == '~':
+			addKey("Return")
+		default:
+			if err := addChar(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if inGroup {
+		return nil, fmt.Errorf("unclosed modifier group")
+	}
+	return strokes, nil
+}
+
+// parseAutoTypeToken handles the text between braces: special keys, delays and placeholders
+func parseAutoTypeToken(token string, strokes *[]keystroke, addKey func(string), addText func(string, bool),
+	expander *fieldExpander, entry *gokeepasslib.Entry, modified bool) error {
+	upper := strings.ToUpper(token)
+
+	if value, ok := strings.CutPrefix(upper, "DELAY="); ok {
+		ms, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || ms < 0 {
+			return fmt.Errorf("invalid keystroke delay '{%s}'", token)
+		}
+		*strokes = append(*strokes, keystroke{kind: strokeSetDelay, delay: time.Duration(ms) * time.Millisecond})
+		return nil
+	}
+
+	name, countStr, hasCount := strings.Cut(upper, " ")
+	count := 1
+	if hasCount && !strings.HasPrefix(upper, "S:") && !strings.HasPrefix(upper, "REF:") {
+		n, err := strconv.Atoi(strings.TrimSpace(countStr))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid count in '{%s}'", token)
+		}
+		count = n
+	}
+
+	switch {
+	case name == "DELAY" && hasCount:
+		*strokes = append(*strokes, keystroke{kind: strokePause, delay: time.Duration(count) * time.Millisecond})
+		return nil
+	case name == "CLEARFIELD":
+		*strokes = append(*strokes,
+			keystroke{kind: strokeKey, text: "a", mods: []string{"ctrl"}},
+			keystroke{kind: strokeKey, text: "BackSpace"})
+		return nil
+	case autoTypeKeys[name] != "":
+		for n := 0; n < count; n++ {
+			addKey(autoTypeKeys[name])
+		}
+		return nil
+	}
+
+	if modified {
+		return fmt.Errorf("modifiers can't be applied to '{%s}'", token)
+	}
+	value, ok, err := expander.placeholder(entry, token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("unsupported placeholder '{%s}'", token)
+	}
+	addText(value, isSecretPlaceholder(entry, upper))
+	return nil
+}
+
+// isSecretPlaceholder reports whether a placeholder expands to a password or protected field
+func isSecretPlaceholder(entry *gokeepasslib.Entry, upper string) bool {
+	if upper == "PASSWORD" || strings.HasPrefix(upper, "REF:P@") {
+		return true
+	}
+	if key, ok := strings.CutPrefix(upper, "S:"); ok {
+		for _, value := range entry.Values {
+			if strings.EqualFold(value.Key, key) {
+				return value.Value.Protected.Bool
+			}
+		}
+	}
+	return false
+}
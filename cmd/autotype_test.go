@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+func TestParseAutoTypeSequence(t *testing.T) {
+	target := newTestEntry("Title", "db", "Password", "db-password")
+	entry := newTestEntry("Title", "web", "UserName", "bob", "Password", "hunter2")
+	entry.Values = append(entry.Values, mkProtectedValue("PIN", "1234"), mkValue("Team", "ops"))
+	db := newTestDatabase("password", newTestGroup("Root", target, entry))
+	entry = db.Content.Root.Groups[0].Entries[1]
+
+	tests := []struct {
+		sequence string
+		want     []string
+		err      bool
+	}{
+		{"{USERNAME}{TAB}{PASSWORD}{ENTER}", []string{`type "bob"`, "key  Tab", "type ********", "key  Return"}, false},
+		{"a{}}b{{}", []string{`type "a}b{"`}, false},
+		{"{+}{^}{%}{~}{(}{)}", []string{`type "+^%~()"`}, false},
+		{"^(ac)", []string{"key  ctrl+a", "key  ctrl+c"}, false},
+		{"+^x", []string{"key  shift+ctrl+x"}, false},
+		{"^{TAB}", []string{"key  ctrl+Tab"}, false},
+		{"{TAB 3}", []string{"key  Tab", "key  Tab", "key  Tab"}, false},
+		{"{TAB 0}x", []string{`type "x"`}, false},
+		{"~", []string{"key  Return"}, false},
+		{"{DELAY=50}a{DELAY 200}b", []string{"set keystroke delay to 50ms", `type "a"`, "wait 200ms", `type "b"`}, false},
+		{"{S:PIN}{S:Team}", []string{"type ********", `type "ops"`}, false},
+		{fmt.Sprintf("{REF:P@I:%x}", target.UUID), []string{"type ********"}, false},
+		{fmt.Sprintf("{REF:T@I:%x}", target.UUID), []string{`type "db"`}, false},
+		{"{CLEARFIELD}", []string{"key  ctrl+a", "key  BackSpace"}, false},
+		{"{TAB", nil, true},
+		{"^(ab", nil, true},
+		{"{TAB x}", nil, true},
+		{"{DELAY=-1}", nil, true},
+		{"^{PASSWORD}", nil, true},
+		{"^!", nil, true},
+		{"{NOSUCHPLACEHOLDER}", nil, true},
+	}
+	for _, tt := range tests {
+		strokes, err := parseAutoTypeSequence(tt.sequence, newFieldExpander(db), &entry)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseAutoTypeSequence(%q) = %v, want an error", tt.sequence, strokes)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseAutoTypeSequence(%q) error = %v", tt.sequence, err)
+			continue
+		}
+		var got []string
+		for _, stroke := range strokes {
+			got = append(got, stroke.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("parseAutoTypeSequence(%q) = %q, want %q", tt.sequence, got, tt.want)
+		}
+	}
+}
+
+func TestAutoTypeEnabled(t *testing.T) {
+	disabledGroup := &gokeepasslib.Group{EnableAutoType: w.NewNullableBoolWrapper(false)}
+	enabledGroup := &gokeepasslib.Group{EnableAutoType: w.NewNullableBoolWrapper(true)}
+	unsetGroup := &gokeepasslib.Group{}
+
+	tests := []struct {
+		name     string
+		autoType gokeepasslib.AutoTypeData
+		groups   []*gokeepasslib.Group
+		want     bool
+	}{
+		{"missing element", gokeepasslib.AutoTypeData{}, nil, true},
+		{"enabled", gokeepasslib.AutoTypeData{Enabled: w.NewBoolWrapper(true)}, nil, true},
+		{"disabled with a sequence", gokeepasslib.AutoTypeData{DefaultSequence: "{PASSWORD}"}, nil, false},
+		{"disabled with an association", gokeepasslib.AutoTypeData{Associations: []gokeepasslib.AutoTypeAssociation{{Window: "*"}}}, nil, false},
+		{"disabled group", gokeepasslib.AutoTypeData{Enabled: w.NewBoolWrapper(true)}, []*gokeepasslib.Group{disabledGroup}, false},
+		{"nearest group wins", gokeepasslib.AutoTypeData{Enabled: w.NewBoolWrapper(true)}, []*gokeepasslib.Group{unsetGroup, enabledGroup, disabledGroup}, true},
+	}
+	for _, tt := range tests {
+		entry := gokeepasslib.Entry{AutoType: tt.autoType}
+		if got := autoTypeEnabled(&entry, tt.groups); got != tt.want {
+			t.Errorf("%s: autoTypeEnabled = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// breachDB looks up SHA-1 password hashes in a local copy of the Have I Been Pwned
+// Pwned Passwords list. Three layouts are supported:
+//
+//   - a file of "HASH:COUNT" lines sorted by hash, searched with a binary search
+//   - a folder of range files named PREFIX.txt holding "SUFFIX:COUNT" lines
+//   - a single range file named PREFIX.txt
+type breachDB struct {
+	path string
+
+	// Set for the sorted hash list
+	file *os.File
+	size int64
+
+	// Set for range files
+	rangeDir  string
+	rangeFile string
+}
+
+var rangeFileName = regexp.MustCompile(`^[0-9A-Fa-f]{5}\.txt$`)
+
+func openBreachDB(path string) (*breachDB, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &breachDB{path: path, rangeDir: path}, nil
+	}
+	if rangeFileName.MatchString(filepath.Base(path)) {
+		return &breachDB{path: path, rangeFile: path}, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &breachDB{path: path, file: file, size: info.Size()}, nil
+}
+
+func (b *breachDB) close() {
+	if b.file != nil {
+		b.file.Close()
+	}
+}
+
+// passwordBreachCount returns how often a password appears in the breach list
+func (b *breachDB) passwordBreachCount(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	return b.count(strings.ToUpper(hex.EncodeToString(sum[:])))
+}
+
+func (b *breachDB) count(hash string) (int, error) {
+	prefix, suffix := hash[:5], hash[5:]
+	switch {
+	case b.rangeDir != "":
+		file, err := os.Open(filepath.Join(b.rangeDir, prefix+".txt"))
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		return scanRangeFile(file, suffix)
+	case b.rangeFile != "":
+		if !strings.EqualFold(strings.TrimSuffix(filepath.Base(b.rangeFile), ".txt"), prefix) {
+			return 0, nil
+		}
+		file, err := os.Open(b.rangeFile)
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		return scanRangeFile(file, suffix)
+	default:
+		return b.searchSorted(hash)
+	}
+}
+
+// scanRangeFile finds suffix in a range file of "SUFFIX:COUNT" lines
+func scanRangeFile(r io.Reader, suffix string) (int, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineHash, count := parseBreachLine(scanner.Text())
+		if strings.EqualFold(lineHash, suffix) {
+			return count, nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// searchSorted binary searches the sorted hash list without reading it into memory.
+// lo is always the start of a line; every line starting at or after hi sorts after hash.
+func (b *breachDB) searchSorted(hash string) (int, error) {
+	lo, hi := int64(0), b.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, start, end, err := b.lineFrom(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi || line == "" {
+			hi = mid
+			continue
+		}
+
+		lineHash, count := parseBreachLine(line)
+		switch strings.Compare(strings.ToUpper(lineHash), hash) {
+		case 0:
+			return count, nil
+		case -1:
+			lo = end
+		default:
+			hi = mid
+		}
+	}
+	return 0, nil
+}
+
+// lineFrom returns the first line starting at or after offset, with its start and end offsets
+func (b *breachDB) lineFrom(offset int64) (string, int64, int64, error) {
+	start := offset
+	if offset > 0 {
+		// Back up one byte so a line starting exactly at offset is found
+		start = offset - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(b.file, start, b.size-start))
+	if offset > 0 {
+		skipped, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return "", b.size, b.size, nil
+		}
+		if err != nil {
+			return "", 0, 0, err
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return "", 0, 0, err
+	}
+	end := start + int64(len(line))
+	return string(bytes.TrimRight(line, "\r\n")), start, end, nil
+}
+
+// parseBreachLine splits a "HASH:COUNT" line
+func parseBreachLine(line string) (string, int) {
+	hash, countStr, _ := strings.Cut(strings.TrimSpace(line), ":")
+	count, err := strconv.Atoi(countStr)
+	if err != nil {
+		count = 1
+	}
+	return hash, count
+}
+
+func (b *breachDB) String() string {
+	switch {
+	case b.rangeDir != "":
+		return fmt.Sprintf("range files in %s", b.path)
+	case b.rangeFile != "":
+		return fmt.Sprintf("range file %s", b.path)
+	default:
+		return fmt.Sprintf("sorted hash list %s", b.path)
+	}
+}
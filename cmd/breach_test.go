@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBreachDBCount(t *testing.T) {
+	// hashes are sorted by construction, each with its own prefix
+	var hashes []string
+	for i := 1; i <= 50; i++ {
+		hashes = append(hashes, fmt.Sprintf("%05X%035X", i*1000, i))
+	}
+	lookups := []struct {
+		name string
+		hash string
+		want int
+	}{
+		{"first line", hashes[0], 1},
+		{"middle line", hashes[24], 25},
+		{"last line", hashes[49], 50},
+		{"missing between two lines", fmt.Sprintf("%05X%035X", 10*1000, 11), 0},
+		{"missing before the first line", strings.Repeat("0", 40), 0},
+		{"missing after the last line", strings.Repeat("F", 40), 0},
+	}
+
+	sortedList := func(newline string, format func(i int, hash string) string) string {
+		var b strings.Builder
+		for i, hash := range hashes {
+			b.WriteString(format(i+1, hash) + newline)
+		}
+		return b.String()
+	}
+	withCount := func(i int, hash string) string { return fmt.Sprintf("%s:%d", hash, i) }
+	tests := []struct {
+		name       string
+		content    string
+		withCounts bool
+	}{
+		{"LF", sortedList("\n", withCount), true},
+		{"CRLF", sortedList("\r\n", withCount), true},
+		{"lowercase hex", sortedList("\n", func(i int, hash string) string { return strings.ToLower(withCount(i, hash)) }), true},
+		{"no trailing newline", strings.TrimSuffix(sortedList("\n", withCount), "\n"), true},
+		{"without counts", sortedList("\n", func(_ int, hash string) string { return hash }), false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "pwned-passwords-sha1-ordered-by-hash.txt")
+		if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		db, err := openBreachDB(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, lookup := range lookups {
+			want := lookup.want
+			if !tt.withCounts && want > 0 {
+				want = 1
+			}
+			if got, err := db.count(lookup.hash); err != nil || got != want {
+				t.Errorf("%s, %s: count = %d, %v, want %d", tt.name, lookup.name, got, err, want)
+			}
+		}
+		db.close()
+	}
+
+	// The same hashes as range files, with lowercase suffixes and CRLF line endings in one
+	rangeDir := t.TempDir()
+	for i, hash := range hashes {
+		line := fmt.Sprintf("%s:%d\n", hash[5:], i+1)
+		switch i {
+		case 0:
+			line = strings.ToLower(line)
+		case 1:
+			line = strings.Replace(line, "\n", "\r\n", 1)
+		}
+		if err := os.WriteFile(filepath.Join(rangeDir, hash[:5]+".txt"), []byte(line), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := openBreachDB(rangeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range hashes[:2] {
+		if got, err := db.count(hash); err != nil || got == 0 {
+			t.Errorf("range directory: count(%s) = %d, %v, want a match", hash, got, err)
+		}
+	}
+	for _, lookup := range lookups {
+		if got, err := db.count(lookup.hash); err != nil || got != lookup.want {
+			t.Errorf("range directory, %s: count = %d, %v, want %d", lookup.name, got, err, lookup.want)
+		}
+	}
+
+	rangeFile := filepath.Join(rangeDir, hashes[24][:5]+".txt")
+	db, err = openBreachDB(rangeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db.count(hashes[24]); err != nil || got != 25 {
+		t.Errorf("range file: count = %d, %v, want 25", got, err)
+	}
+	if got, err := db.count(hashes[23]); err != nil || got != 0 {
+		t.Errorf("range file, other prefix: count = %d, %v, want 0", got, err)
+	}
+}
+
+func TestPasswordBreachCount(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	path := filepath.Join(t.TempDir(), "5BAA6.txt")
+	if err := os.WriteFile(path, []byte("003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	db, err := openBreachDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db.passwordBreachCount("password"); err != nil || got != 9545824 {
+		t.Errorf("passwordBreachCount(password) = %d, %v, want 9545824", got, err)
+	}
+	if got, err := db.passwordBreachCount("not in the list"); err != nil || got != 0 {
+		t.Errorf("passwordBreachCount of a missing password = %d, %v, want 0", got, err)
+	}
+}
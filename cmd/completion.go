@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// completionDB opens the GoKP database for shell completion. Completion runs on every tab
+// press and must never prompt, so it only uses the password saved with `gokp auth login`
+// and returns nil without one.
+func completionDB(cmd *cobra.Command) *gokeepasslib.Database {
+	secret, err := get_password("gokp", "local")
+	if err != nil {
+		return nil
+	}
+	test := false
+	if cmd.Flags().Lookup("test") != nil {
+		test, _ = cmd.Flags().GetBool("test")
+	}
+	_, _, gokpKDBX := pathSelection(test)
+	db, err := decodeKeepassDB(gokpKDBX, secret)
+	if err != nil {
+		return nil
+	}
+	return db
+}
+
+// completeDatabaseNames completes the names of the registered databases, described by
+// their paths. Names already given as arguments are left out.
+func completeDatabaseNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	db := completionDB(cmd)
+	if db == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	databasesGroup := FindRootGroupByName(db.Content.Root.Groups, databasesGroupName)
+	if databasesGroup == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	given := map[string]bool{}
+	for _, arg := range args {
+		given[arg] = true
+	}
+	var names []string
+	for _, dbEntry := range databasesGroup.Entries {
+		name := dbEntry.GetTitle()
+		if !given[name] && strings.HasPrefix(name, toComplete) {
+			names = append(names, fmt.Sprintf("%s\t%s", name, getEntryAttribute(&dbEntry, attrDatabasePath)))
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFirstDatabaseName completes a database name for commands taking a single one
+func completeFirstDatabaseName(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeDatabaseNames(cmd, args, toComplete)
+}
+
+// completeGroupNames completes the root group names of the external databases, read from
+// their stored search indexes so no external database is decrypted. The --database flag,
+// if set, limits them to one database.
+func completeGroupNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	db := completionDB(cmd)
+	if db == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	databasesGroup := FindRootGroupByName(db.Content.Root.Groups, databasesGroupName)
+	if databasesGroup == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	target, _ := cmd.Flags().GetString("database")
+
+	// groups maps each group name to the databases holding it
+	groups := map[string]map[string]bool{}
+	for _, dbEntry := range databasesGroup.Entries {
+		name := dbEntry.GetTitle()
+		if target != "" && name != target {
+			continue
+		}
+		index := readSearchIndex(db, name)
+		if index == nil {
+			continue
+		}
+		for _, entry := range index.Entries {
+			if len(entry.Group) == 0 || !strings.HasPrefix(entry.Group[0], toComplete) {
+				continue
+			}
+			if groups[entry.Group[0]] == nil {
+				groups[entry.Group[0]] = map[string]bool{}
+			}
+			groups[entry.Group[0]][name] = true
+		}
+	}
+
+	var names []string
+	for group, databases := range groups {
+		var holders []string
+		for name := range databases {
+			holders = append(holders, name)
+		}
+		sort.Strings(holders)
+		names = append(names, fmt.Sprintf("%s\t%s", group, strings.Join(holders, ", ")))
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFavoriteIndexes completes favorite indexes, described by their titles
+func completeFavoriteIndexes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	db := completionDB(cmd)
+	if db == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	favoritesGroup := FindRootGroupByName(db.Content.Root.Groups, favoritesGroupName)
+	if favoritesGroup == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var indexes []string
+	for _, favorite := range favoritesGroup.Entries {
+		index := getEntryAttribute(&favorite, attrFavoriteIndex)
+		if index != "" && strings.HasPrefix(index, toComplete) {
+			description := fmt.Sprintf("%s (%s)", favorite.GetTitle(), getEntryAttribute(&favorite, attrDatabaseSource))
+			indexes = append(indexes, fmt.Sprintf("%s\t%s", index, description))
+		}
+	}
+	return indexes, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -14,6 +13,7 @@ import (
 func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.Flags().StringP("clipboard-timeout", "c", "", "Set clipboard timeout in seconds (default: 30)")
+	configCmd.Flags().String("strict-permissions", "", "Refuse to open secret files readable by group or others (true/false)")
 	configCmd.Flags().BoolP("test", "t", false, "Run CLI command in test mode")
 }
 
@@ -22,50 +22,85 @@ var configCmd = &cobra.Command{
 	Short: "Manage gokp configuration",
 	Long: `Manage gokp configuration settings.
 This command allows you to view and modify the gokp configuration file.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
-			printConfig()
-			return
+			return printConfig()
 		}
 
 		subCommand := args[0]
-		config := readConfig()
+		config, err := readConfig(false)
+		if err != nil {
+			return err
+		}
 
 		switch subCommand {
 		case "read":
 			fmt.Printf("Current Configuration:\n")
 			fmt.Printf("- Clipboard Timeout: %d seconds\n", config.ClipboardTimeout)
+			fmt.Printf("- Strict Permissions: %t\n", config.StrictPermissions)
 		case "update":
 			timeoutStr, _ := cmd.Flags().GetString("clipboard-timeout")
-			if timeoutStr == "" {
-				fmt.Println("ERROR: Please provide a clipboard timeout value using --clipboard-timeout flag.")
-				os.Exit(0)
+			strictStr, _ := cmd.Flags().GetString("strict-permissions")
+			if timeoutStr == "" && strictStr == "" {
+				return newError(ErrUsage, "please provide a value using the --clipboard-timeout or --strict-permissions flag")
 			}
 			if timeoutStr != "" {
 				timeoutInt, err := strconv.Atoi(timeoutStr)
 				if err != nil || timeoutInt <= 0 {
-					log.Fatal("Invalid clipboard timeout value. Must be a positive integer.")
+					return newError(ErrUsage, "invalid clipboard timeout value. Must be a positive integer")
 				}
 				config.ClipboardTimeout = timeoutInt
 			}
-			err := saveConfig(config)
-			if err != nil {
-				log.Fatalf("Error saving config: %v", err)
+			if strictStr != "" {
+				strict, err := strconv.ParseBool(strictStr)
+				if err != nil {
+					return newError(ErrUsage, "invalid strict permissions value. Must be true or false")
+				}
+				config.StrictPermissions = strict
+			}
+			if err := saveConfig(config, false); err != nil {
+				return fmt.Errorf("error saving config: %w", err)
 			}
 			fmt.Println("Configuration updated successfully.")
 		default:
-			log.Fatalf("Unknown command: %s", args[0])
+			return newError(ErrUsage, "unknown command: %s", args[0])
 		}
+		return nil
 	},
 }
 
 type Config struct {
-	ClipboardTimeout int `json:"clipboard-timeout"`
+	SchemaVersion     int  `json:"schema-version"`
+	ClipboardTimeout  int  `json:"clipboard-timeout"`
+	StrictPermissions bool `json:"strict-permissions"`
 }
 
-func readConfig() *Config {
+func readConfig(test bool) (*Config, error) {
+	config, err := readConfigFile(test)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("Config file not found, creating with defaults...")
+			return createDefaultConfig(test)
+		}
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	if _, err := migrateConfig(config); err != nil {
+		return nil, fmt.Errorf("error migrating config: %w", err)
+	}
+
+	return config, nil
+}
 
-	gokpFolder, _, _ := pathSelection(false)
+// readConfigFile reads config.json exactly as stored on disk, without
+// creating defaults or applying migrations
+func readConfigFile(test bool) (*Config, error) {
+	gokpFolder, _, _ := pathSelection(test)
+	return readConfigFileIn(gokpFolder)
+}
+
+// readConfigFileIn reads the config.json kept in a gokp folder, next to its gokp.kdbx
+func readConfigFileIn(gokpFolder string) (*Config, error) {
 	configPath := filepath.Join(gokpFolder, "config.json")
 
 	config := &Config{
@@ -74,25 +109,19 @@ func readConfig() *Config {
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Println("Config file not found, creating with defaults...")
-			config = createDefaultConfig()
-			return config
-
-		}
-		log.Fatalf("Error reading config file: %v", err)
+		return nil, err
 	}
 
 	err = json.Unmarshal(data, config)
 	if err != nil {
-		log.Fatalf("Error unmarshalling JSON: %v", err)
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
 	}
 
-	return config
+	return config, nil
 }
 
-func saveConfig(config *Config) error {
-	gokpFolder, _, _ := pathSelection(false)
+func saveConfig(config *Config, test bool) error {
+	gokpFolder, _, _ := pathSelection(test)
 	configPath := filepath.Join(gokpFolder, "config.json")
 
 	data, err := json.MarshalIndent(config, "", "    ")
@@ -100,7 +129,7 @@ func saveConfig(config *Config) error {
 		return fmt.Errorf("error marshalling config to JSON: %v", err)
 	}
 
-	err = os.WriteFile(configPath, data, 0644)
+	err = writeSecretFile(configPath, data)
 	if err != nil {
 		return fmt.Errorf("error writing config file: %v", err)
 	}
@@ -110,28 +139,37 @@ func saveConfig(config *Config) error {
 }
 
 func updateConfig(clipboardTimeout int) error {
-	config := readConfig()
+	config, err := readConfig(false)
+	if err != nil {
+		return err
+	}
 	if clipboardTimeout > 0 {
 		config.ClipboardTimeout = clipboardTimeout
 	}
-	return saveConfig(config)
+	return saveConfig(config, false)
 }
 
-func createDefaultConfig() *Config {
+func createDefaultConfig(test bool) (*Config, error) {
 	config := &Config{
+		SchemaVersion:    schemaVersion,
 		ClipboardTimeout: 30,
 	}
 
-	error := saveConfig(config)
-	if error != nil {
-		log.Fatalf("Failed to create default config: %v", error)
+	if err := saveConfig(config, test); err != nil {
+		return nil, fmt.Errorf("failed to create default config: %w", err)
 	}
 
-	return config
+	return config, nil
 }
 
-func printConfig() {
-	config := readConfig()
+func printConfig() error {
+	config, err := readConfig(false)
+	if err != nil {
+		return err
+	}
 	fmt.Printf("Current Configuration:\n")
+	fmt.Printf("- Schema Version: %d\n", config.SchemaVersion)
 	fmt.Printf("- Clipboard TImeout: %d seconds\n", config.ClipboardTimeout)
+	fmt.Printf("- Strict Permissions: %t\n", config.StrictPermissions)
+	return nil
 }
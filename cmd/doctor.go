@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/atotto/clipboard"
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/zalando/go-keyring"
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolP("migrate", "m", false, "Upgrade config.json and the gokp database to the current schema")
+	doctorCmd.Flags().Bool("fix-perms", false, "Restrict the .gokp folder to 0700 and its files to 0600")
+	doctorCmd.Flags().BoolP("test", "t", false, "Run CLI command in test mode")
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check and repair the gokp setup",
+	Long: `Check and repair the gokp setup.
+
+Without flags, runs a health check of the gokp database, every registered external
+database, favorites, the clipboard, the OS keystore and the permissions on the
+.gokp folder. Exits with status 1 if any check fails.
+
+Examples:
+  gokp doctor             # Run all health checks
+  gokp doctor --migrate   # Upgrade config.json and gokp.kdbx to the current schema
+  gokp doctor --fix-perms # Restrict permissions on the .gokp folder and its files`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrate, _ := cmd.Flags().GetBool("migrate")
+		fixPerms, _ := cmd.Flags().GetBool("fix-perms")
+		test, _ := cmd.Flags().GetBool("test")
+
+		if fixPerms {
+			gokpFolder, _, _ := pathSelection(test)
+			changed, err := fixGoKPPermissions(gokpFolder)
+			for _, change := range changed {
+				fmt.Printf("Fixed %s\n", change)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to fix permissions: %w", err)
+			}
+			if len(changed) == 0 {
+				fmt.Printf("Permissions on %s are already restricted.\n", gokpFolder)
+			}
+			if !migrate {
+				return nil
+			}
+		}
+
+		if migrate {
+			if err := migrateConfigFile(test); err != nil {
+				return err
+			}
+			return migrateGoKPFile(test)
+		}
+
+		report := runHealthChecks(test)
+		report.print()
+		if report.failed() {
+			return errors.New("some health checks failed")
+		}
+		return nil
+	},
+}
+
+// doctorCheck is the outcome of a single health check
+type doctorCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+type doctorReport struct {
+	checks []doctorCheck
+}
+
+func (r *doctorReport) pass(name, detail string) {
+	r.checks = append(r.checks, doctorCheck{Name: name, OK: true, Detail: detail})
+}
+
+func (r *doctorReport) fail(name, detail string) {
+	r.checks = append(r.checks, doctorCheck{Name: name, OK: false, Detail: detail})
+}
+
+func (r *doctorReport) failed() bool {
+	for _, check := range r.checks {
+		if !check.OK {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *doctorReport) print() {
+	passed := 0
+	for _, check := range r.checks {
+		if check.OK {
+			passed++
+			fmt.Printf("[%sPASS%s] %s: %s\n", ColorBoldGreen, ColorReset, check.Name, check.Detail)
+		} else {
+			fmt.Printf("[%sFAIL%s] %s: %s\n", ColorBoldRed, ColorReset, check.Name, check.Detail)
+		}
+	}
+	fmt.Printf("\n%d of %d checks passed.\n", passed, len(r.checks))
+}
+
+func runHealthChecks(test bool) *doctorReport {
+	report := &doctorReport{}
+	gokpFolder, _, gokpKDBX := pathSelection(test)
+
+	checkKeyring(report)
+	checkClipboard(report)
+	checkPermissions(report, gokpFolder, gokpKDBX)
+
+	if _, err := os.Stat(gokpKDBX); err != nil {
+		report.fail("gokp database", fmt.Sprintf("%s not found, run `gokp setup init`", gokpKDBX))
+		return report
+	}
+
+	secret, err := getGoKPPassword(gokpKDBX)
+	if err != nil {
+		report.fail("gokp database", fmt.Sprintf("failed to get GoKP password: %v", err))
+		return report
+	}
+
+	// Decode without migrating so the checks below see the layout stored on disk
+	db, err := decodeKeepassDB(gokpKDBX, secret)
+	if err != nil {
+		report.fail("gokp database", fmt.Sprintf("failed to open %s: %v", gokpKDBX, err))
+		return report
+	}
+	report.pass("gokp database", fmt.Sprintf("%s opened", gokpKDBX))
+
+	if version := getDBSchemaVersion(db); version < schemaVersion {
+		report.fail("schema", fmt.Sprintf("gokp database is at schema version %d, run `gokp doctor --migrate`", version))
+	} else {
+		report.pass("schema", fmt.Sprintf("gokp database is at schema version %d", version))
+	}
+
+	databasesGroup := FindRootGroupByName(db.Content.Root.Groups, databasesGroupName)
+	favoritesGroup := FindRootGroupByName(db.Content.Root.Groups, favoritesGroupName)
+	for _, name := range []string{databasesGroupName, favoritesGroupName} {
+		if FindRootGroupByName(db.Content.Root.Groups, name) == nil {
+			report.fail("group "+name, "missing from gokp database")
+		} else {
+			report.pass("group "+name, "present")
+		}
+	}
+	if databasesGroup == nil {
+		return report
+	}
+
+	externalDBs := checkExternalDatabases(report, databasesGroup)
+	if favoritesGroup != nil {
+		checkFavorites(report, favoritesGroup, externalDBs)
+	}
+
+	return report
+}
+
+func checkKeyring(report *doctorReport) {
+	_, err := get_password("gokp", "local")
+	switch {
+	case err == nil:
+		report.pass("keyring", "reachable, gokp password stored")
+	case errors.Is(err, keyring.ErrNotFound):
+		report.pass("keyring", "reachable, no gokp password stored")
+	default:
+		report.fail("keyring", fmt.Sprintf("unreachable: %v", err))
+	}
+}
+
+func checkClipboard(report *doctorReport) {
+	if clipboard.Unsupported {
+		report.fail("clipboard", "no clipboard backend available (install xclip, xsel or wl-clipboard)")
+		return
+	}
+	if _, err := clipboard.ReadAll(); err != nil {
+		report.fail("clipboard", fmt.Sprintf("backend error: %v", err))
+		return
+	}
+	report.pass("clipboard", "backend available")
+}
+
+func checkPermissions(report *doctorReport, gokpFolder, gokpKDBX string) {
+	paths := []string{gokpFolder, gokpKDBX, filepath.Join(gokpFolder, "config.json")}
+	for _, path := range paths {
+		insecure, mode, err := isGroupOrWorldAccessible(path)
+		if err != nil {
+			continue
+		}
+		if insecure {
+			report.fail("permissions", fmt.Sprintf("%s is accessible by group or others (%04o), run `gokp doctor --fix-perms`", path, mode))
+		} else {
+			report.pass("permissions", fmt.Sprintf("%s (%04o)", path, mode))
+		}
+	}
+}
+
+// checkExternalDatabases verifies every registered database and key file exists and decrypts,
+// returning the databases that opened keyed by name
+func checkExternalDatabases(report *doctorReport, databasesGroup *gokeepasslib.Group) map[string]*gokeepasslib.Database {
+	opened := map[string]*gokeepasslib.Database{}
+
+	if len(databasesGroup.Entries) == 0 {
+		report.pass("external databases", "none registered")
+		return opened
+	}
+
+	for _, dbEntry := range databasesGroup.Entries {
+		dbName := dbEntry.GetTitle()
+		name := "database " + dbName
+		dbPath := getEntryAttribute(&dbEntry, attrDatabasePath)
+		keyFilePath := getEntryAttribute(&dbEntry, attrKeyFilePath)
+
+		if dbPath == "" {
+			report.fail(name, "no path configured")
+			continue
+		}
+		if _, err := os.Stat(dbPath); err != nil {
+			report.fail(name, fmt.Sprintf("file %s not found", dbPath))
+			continue
+		}
+		if keyFilePath != "" {
+			if _, err := os.Stat(keyFilePath); err != nil {
+				report.fail(name, fmt.Sprintf("key file %s not found", keyFilePath))
+				continue
+			}
+			if insecure, mode, _ := isGroupOrWorldAccessible(keyFilePath); insecure {
+				report.fail(name, fmt.Sprintf("key file %s is accessible by group or others (%04o)", keyFilePath, mode))
+			}
+		}
+
+		externalDB, err := openExternalKeepassDB(dbPath, dbEntry.GetPassword(), keyFilePath)
+		if err != nil {
+			report.fail(name, err.Error())
+			continue
+		}
+		opened[dbName] = externalDB
+		report.pass(name, fmt.Sprintf("%s opened", dbPath))
+	}
+
+	return opened
+}
+
+// checkFavorites verifies every favorite still points to an existing entry in its source database
+func checkFavorites(report *doctorReport, favoritesGroup *gokeepasslib.Group, externalDBs map[string]*gokeepasslib.Database) {
+	if len(favoritesGroup.Entries) == 0 {
+		report.pass("favorites", "none saved")
+		return
+	}
+
+	uuids := map[string]map[string]bool{}
+	for dbName, externalDB := range externalDBs {
+		uuids[dbName] = map[string]bool{}
+		forEachEntry(externalDB.Content.Root.Groups, nil, func(_ []string, entry *gokeepasslib.Entry) {
+			uuids[dbName][fmt.Sprintf("%x", entry.UUID)] = true
+		})
+	}
+
+	for _, favorite := range favoritesGroup.Entries {
+		name := fmt.Sprintf("favorite %s (%s)", favorite.GetContent(attrFavoriteIndex), favorite.GetTitle())
+		source := favorite.GetContent(attrDatabaseSource)
+		uuid := favorite.GetContent(attrDatabaseUUID)
+
+		known, ok := uuids[source]
+		if !ok {
+			report.fail(name, fmt.Sprintf("source database '%s' is not registered or did not open", source))
+			continue
+		}
+		if !known[uuid] {
+			report.fail(name, fmt.Sprintf("entry %s no longer exists in '%s'", uuid, source))
+			continue
+		}
+		report.pass(name, fmt.Sprintf("found in '%s'", source))
+	}
+}
+
+func migrateConfigFile(test bool) error {
+	config, err := readConfigFile(test)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("config.json: not found, skipping.")
+			return nil
+		}
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	from := config.SchemaVersion
+	applied, err := migrateConfig(config)
+	if err != nil {
+		return fmt.Errorf("error migrating config: %w", err)
+	}
+	if len(applied) == 0 {
+		fmt.Printf("config.json: already at schema version %d.\n", schemaVersion)
+		return nil
+	}
+
+	if err := saveConfig(config, test); err != nil {
+		return fmt.Errorf("error saving config: %w", err)
+	}
+	fmt.Printf("config.json: migrated from schema version %d to %d.\n", from, schemaVersion)
+	for _, description := range applied {
+		fmt.Printf("- %s\n", description)
+	}
+	return nil
+}
+
+func migrateGoKPFile(test bool) error {
+	_, _, gokpKDBX := pathSelection(test)
+
+	secret, err := getGoKPPassword(gokpKDBX)
+	if err != nil {
+		return fmt.Errorf("failed to get GoKP password: %w", err)
+	}
+
+	db, err := decodeKeepassDB(gokpKDBX, secret)
+	if err != nil {
+		return fmt.Errorf("failed to open Keepass database: %w", err)
+	}
+
+	from := getDBSchemaVersion(db)
+	applied, err := migrateGoKPDB(db)
+	if err != nil {
+		return fmt.Errorf("failed to migrate gokp database: %w", err)
+	}
+	if len(applied) == 0 {
+		fmt.Printf("gokp.kdbx: already at schema version %d.\n", schemaVersion)
+		return nil
+	}
+
+	if err := saveKeepassDB(db, gokpKDBX); err != nil {
+		return fmt.Errorf("failed to save Keepass database: %w", err)
+	}
+	fmt.Printf("gokp.kdbx: migrated from schema version %d to %d.\n", from, schemaVersion)
+	for _, description := range applied {
+		fmt.Printf("- %s\n", description)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoctorMigrateUsesTestFolder(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	testFolder, _, testKDBX := pathSelection(true)
+	if err := os.MkdirAll(testFolder, gokpDirMode); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveKeepassDB(openTestGoKP(t, gokpKDBX), testKDBX); err != nil {
+		t.Fatal(err)
+	}
+	oldConfig := []byte(`{"clipboard-timeout": 30}`)
+	for _, folder := range []string{filepath.Dir(gokpKDBX), testFolder} {
+		if err := writeSecretFile(filepath.Join(folder, "config.json"), oldConfig); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := runGoKP(t, "doctor", "--migrate", "--test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if config, err := readConfigFile(true); err != nil {
+		t.Fatal(err)
+	} else if config.SchemaVersion != schemaVersion {
+		t.Errorf("test config.json schema version = %d, want %d", config.SchemaVersion, schemaVersion)
+	}
+	if config, err := readConfigFile(false); err != nil {
+		t.Fatal(err)
+	} else if config.SchemaVersion != 0 {
+		t.Errorf("config.json outside the test folder was migrated to schema version %d", config.SchemaVersion)
+	}
+}
+
+func TestOutdatedSchemaNoteShownOnce(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	db := openTestGoKP(t, gokpKDBX)
+	setDBSchemaVersion(db, 0)
+	if err := saveKeepassDB(db, gokpKDBX); err != nil {
+		t.Fatal(err)
+	}
+
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+	realStderr := os.Stderr
+	os.Stderr = stderr
+	defer func() { os.Stderr = realStderr }()
+	schemaNoteShown = false
+
+	for i := 0; i < 3; i++ {
+		openTestGoKP(t, gokpKDBX)
+	}
+
+	output, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.Count(string(output), "schema is outdated"); count != 1 {
+		t.Errorf("outdated schema note printed %d times, want once:\n%s", count, output)
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Sentinel errors. Commands wrap them with context and Execute maps them to exit codes,
+// so scripts can tell failures apart with `errors.Is` semantics.
+var (
+	ErrUsage          = errors.New("invalid usage")
+	ErrInputRequired  = errors.New("input required")
+	ErrBadCredentials = errors.New("wrong password or key file")
+	ErrBadKeyFile     = fmt.Errorf("invalid key file: %w", ErrBadCredentials)
+	ErrNotFound       = errors.New("not found")
+	ErrAmbiguous      = errors.New("ambiguous match")
+	ErrLocked         = errors.New("GoKP database is locked")
+	ErrNoDatabases    = errors.New("no external databases")
+	ErrExists         = errors.New("already exists")
+	ErrConflict       = errors.New("changed concurrently")
+	ErrUnsupported    = errors.New("unsupported database format")
+	ErrCorruptFile    = errors.New("corrupted database file")
+)
+
+// errorKinds maps the sentinel errors to their exit codes and JSON codes, documented in
+// the root command's help. Any other error exits with 1.
+var errorKinds = []struct {
+	err      error
+	exitCode int
+	code     string
+}{
+	{ErrUsage, 2, "usage"},
+	{ErrInputRequired, exitInputRequired, "input_required"},
+	{ErrBadKeyFile, 4, "bad_key_file"},
+	{ErrBadCredentials, 4, "bad_credentials"},
+	{ErrNotFound, 5, "not_found"},
+	{ErrAmbiguous, 6, "ambiguous"},
+	{ErrLocked, 7, "locked"},
+	{ErrNoDatabases, 8, "no_databases"},
+	{ErrExists, 9, "exists"},
+	{ErrConflict, 10, "conflict"},
+	{ErrUnsupported, 11, "unsupported"},
+	{ErrCorruptFile, 12, "corrupt_file"},
+}
+
+// errorFormatEnv selects the error output format like --json-errors, e.g. GOKP_ERROR_FORMAT=json
+const errorFormatEnv = "GOKP_ERROR_FORMAT"
+
+var jsonErrors bool
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&jsonErrors, "json-errors", false, "Print errors as JSON objects on stderr (also GOKP_ERROR_FORMAT=json)")
+}
+
+// kindError is an error of one of the sentinel kinds that keeps its own message
+type kindError struct {
+	err  error
+	kind error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.err, e.kind} }
+
+// newError formats an error of the given sentinel kind
+func newError(kind error, format string, args ...any) error {
+	return &kindError{fmt.Errorf(format, args...), kind}
+}
+
+// markError marks err as being of the given sentinel kind
+func markError(kind, err error) error {
+	if err == nil || errors.Is(err, kind) {
+		return err
+	}
+	return &kindError{err, kind}
+}
+
+// exitCodeFor returns the exit code and JSON code for an error
+func exitCodeFor(err error) (int, string) {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind.exitCode, kind.code
+		}
+	}
+	return 1, "error"
+}
+
+// reportError prints an error returned by a command and returns the exit code for it
+func reportError(cmd *cobra.Command, err error) int {
+	exitCode, code := exitCodeFor(err)
+	if jsonErrors || strings.EqualFold(os.Getenv(errorFormatEnv), "json") {
+		output, _ := json.Marshal(struct {
+			Error    string `json:"error"`
+			Code     string `json:"code"`
+			ExitCode int    `json:"exit_code"`
+		}{err.Error(), code, exitCode})
+		fmt.Fprintln(os.Stderr, string(output))
+		return exitCode
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if errors.Is(err, ErrUsage) && cmd != nil {
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+	}
+	return exitCode
+}
+
+// markUsageErrors marks the errors of the argument validators of cmd and its subcommands
+// as usage errors
+func markUsageErrors(cmd *cobra.Command) {
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			return markError(ErrUsage, validate(cmd, args))
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
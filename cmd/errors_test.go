@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		exitCode int
+		code     string
+	}{
+		{"plain error", errors.New("boom"), 1, "error"},
+		{"usage", newError(ErrUsage, "bad flag"), 2, "usage"},
+		{"input required", newError(ErrInputRequired, "no terminal"), 3, "input_required"},
+		{"bad credentials", newError(ErrBadCredentials, "wrong password"), 4, "bad_credentials"},
+		{"bad key file", markError(ErrBadKeyFile, errors.New("short key")), 4, "bad_key_file"},
+		{"not found", newError(ErrNotFound, "no entry"), 5, "not_found"},
+		{"ambiguous", newError(ErrAmbiguous, "2 entries"), 6, "ambiguous"},
+		{"locked", ErrLocked, 7, "locked"},
+		{"no databases", newError(ErrNoDatabases, "none registered"), 8, "no_databases"},
+		{"exists", newError(ErrExists, "taken"), 9, "exists"},
+		{"conflict", newError(ErrConflict, "changed"), 10, "conflict"},
+		{"unsupported", newError(ErrUnsupported, "KDB"), 11, "unsupported"},
+		{"corrupt file", newError(ErrCorruptFile, "bad block"), 12, "corrupt_file"},
+		{"wrapped with %w", fmt.Errorf("failed to open: %w", newError(ErrNotFound, "no file")), 5, "not_found"},
+		{"wrapped twice", fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", ErrAmbiguous)), 6, "ambiguous"},
+		{"marked error keeps its cause", markError(ErrConflict, fmt.Errorf("save: %w", os.ErrExist)), 10, "conflict"},
+		{"marked twice", markError(ErrNotFound, markError(ErrNotFound, errors.New("gone"))), 5, "not_found"},
+		{"earlier kind wins", markError(ErrUsage, newError(ErrNotFound, "no such database")), 2, "usage"},
+		{"joined", errors.Join(errors.New("first"), newError(ErrLocked, "second")), 7, "locked"},
+	}
+	for _, tt := range tests {
+		exitCode, code := exitCodeFor(tt.err)
+		if exitCode != tt.exitCode || code != tt.code {
+			t.Errorf("%s: exitCodeFor = %d, %q, want %d, %q", tt.name, exitCode, code, tt.exitCode, tt.code)
+		}
+	}
+
+	if err := markError(ErrConflict, fmt.Errorf("save: %w", os.ErrExist)); !errors.Is(err, os.ErrExist) || err.Error() != "save: file already exists" {
+		t.Errorf("markError changed the error: %v", err)
+	}
+}
+
+func TestReportErrorJSON(t *testing.T) {
+	err := fmt.Errorf("failed to resolve DB_PASS: %w", newError(ErrAmbiguous, `2 entries match "db"`))
+	want := `{"error":"failed to resolve DB_PASS: 2 entries match \"db\"","code":"ambiguous","exit_code":6}` + "\n"
+
+	defer func() { jsonErrors = false }()
+	jsonErrors = true
+	var exitCode int
+	if got := captureOutput(t, &os.Stderr, func() { exitCode = reportError(runCmd, err) }); got != want {
+		t.Errorf("--json-errors output = %s, want %s", got, want)
+	}
+	if exitCode != 6 {
+		t.Errorf("exit code = %d, want 6", exitCode)
+	}
+
+	jsonErrors = false
+	t.Setenv(errorFormatEnv, "JSON")
+	if got := captureOutput(t, &os.Stderr, func() { reportError(runCmd, err) }); got != want {
+		t.Errorf("%s=JSON output = %s, want %s", errorFormatEnv, got, want)
+	}
+
+	t.Setenv(errorFormatEnv, "")
+	want = fmt.Sprintf("Error: invalid --env 'X', expected NAME=REFERENCE\nRun '%s --help' for usage.\n", runCmd.CommandPath())
+	if got := captureOutput(t, &os.Stderr, func() { reportError(runCmd, newError(ErrUsage, "invalid --env 'X', expected NAME=REFERENCE")) }); got != want {
+		t.Errorf("text output = %q, want %q", got, want)
+	}
+}
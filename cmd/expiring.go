@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func init() {
+	rootCmd.AddCommand(expiringCmd)
+	expiringCmd.Flags().String("within", "14d", "Report entries expiring within this period (e.g. 14d, 2w, 36h)")
+	expiringCmd.Flags().StringP("database", "d", "", "Check only a specific external database")
+	expiringCmd.RegisterFlagCompletionFunc("database", completeDatabaseNames)
+}
+
+// expiryWarningWindow is how far ahead favorites and search results warn about expiry
+const expiryWarningWindow = 14 * 24 * time.Hour
+
+var expiringCmd = &cobra.Command{
+	Use:   "expiring",
+	Short: "List entries that have expired or expire soon",
+	Long: `List the entries of the external databases that have expired or will expire within
+the given period, soonest first. Entries in the recycle bin are skipped.
+
+Examples:
+  gokp expiring
+  gokp expiring --within 30d -d work`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		withinStr, _ := cmd.Flags().GetString("within")
+		targetDatabase, _ := cmd.Flags().GetString("database")
+
+		within, err := parseDayDuration(withinStr)
+		if err != nil {
+			return fmt.Errorf("invalid --within: %w", err)
+		}
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		type expiringEntry struct {
+			database string
+			path     string
+			uuid     string
+			expires  time.Time
+		}
+		var found []expiringEntry
+		deadline := time.Now().Add(within)
+		for _, external := range openExternalDatabases(gokpDB, targetDatabase, os.Stdout) {
+			forEachActiveEntry(external.DB, func(groupPath []string, entry *gokeepasslib.Entry) {
+				if expires, ok := entryExpiry(*entry); ok && expires.Before(deadline) {
+					found = append(found, expiringEntry{
+						database: external.Name,
+						path:     strings.Join(append(groupPath, entry.GetTitle()), "/"),
+						uuid:     fmt.Sprintf("%x", entry.UUID),
+						expires:  expires,
+					})
+				}
+			})
+		}
+
+		if len(found) == 0 {
+			fmt.Printf("No entries expire within %s.\n", withinStr)
+			return nil
+		}
+		sort.Slice(found, func(i, j int) bool { return found[i].expires.Before(found[j].expires) })
+		for _, entry := range found {
+			status := fmt.Sprintf("%sEXPIRES%s", ColorBoldYellow, ColorReset)
+			if entry.expires.Before(time.Now()) {
+				status = fmt.Sprintf("%sEXPIRED%s", ColorBoldRed, ColorReset)
+			}
+			fmt.Printf("%s %s  %s/%s (%s)\n", status, entry.expires.Local().Format("2006-01-02"), entry.database, entry.path, entry.uuid)
+		}
+		fmt.Printf("\n%d entries expired or expiring within %s.\n", len(found), withinStr)
+		return nil
+	},
+}
+
+// parseDayDuration parses a Go duration that may also use d (days) and w (weeks) units
+func parseDayDuration(value string) (time.Duration, error) {
+	unit, name := time.Duration(0), ""
+	switch {
+	case strings.HasSuffix(value, "d"):
+		unit, name = 24*time.Hour, "days"
+	case strings.HasSuffix(value, "w"):
+		unit, name = 7*24*time.Hour, "weeks"
+	default:
+		return time.ParseDuration(value)
+	}
+	number := value[:len(value)-1]
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a number of %s", number, name)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// entryExpiry returns when an entry expires, if it is set to expire
+func entryExpiry(entry gokeepasslib.Entry) (time.Time, bool) {
+	if !entry.Times.Expires.Bool || entry.Times.ExpiryTime == nil {
+		return time.Time{}, false
+	}
+	return entry.Times.ExpiryTime.Time, true
+}
+
+// expiryWarning returns a banner for entries that have expired or expire within the
+// warning window, or an empty string
+func expiryWarning(entry gokeepasslib.Entry) string {
+	expires, ok := entryExpiry(entry)
+	if !ok {
+		return ""
+	}
+	date := expires.Local().Format("2006-01-02")
+	remaining := time.Until(expires)
+	switch {
+	case remaining <= 0:
+		return fmt.Sprintf("%sWARNING: This entry expired on %s%s", ColorBoldRed, date, ColorReset)
+	case remaining <= expiryWarningWindow:
+		return fmt.Sprintf("%sWARNING: This entry expires in %d day(s), on %s%s", ColorBoldYellow, int(math.Ceil(remaining.Hours()/24)), date, ColorReset)
+	}
+	return ""
+}
+
+// copyEntryExpiry copies the expiry settings of src to dst and reports whether they changed
+func copyEntryExpiry(dst *gokeepasslib.Entry, src gokeepasslib.Entry) bool {
+	srcExpires, srcOK := entryExpiry(src)
+	dstExpires, dstOK := entryExpiry(*dst)
+	if srcOK == dstOK && srcExpires.Equal(dstExpires) {
+		return false
+	}
+	dst.Times.Expires = src.Times.Expires
+	if src.Times.ExpiryTime != nil {
+		expiryTime := *src.Times.ExpiryTime
+		dst.Times.ExpiryTime = &expiryTime
+	}
+	return true
+}
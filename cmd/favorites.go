@@ -3,7 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"os/signal"
 	"strconv"
@@ -38,48 +37,38 @@ func init() {
 }
 
 var favCmd = &cobra.Command{
-	Use:   "fav [INDEX]",
-	Short: "Alias of `favorites`",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Use:               "fav [INDEX]",
+	ValidArgsFunction: completeFavoriteIndexes,
+	Short:             "Alias of `favorites`",
+	Args:              cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		showPassword, _ := cmd.Flags().GetBool("password")
 		copyToClipboard, _ := cmd.Flags().GetBool("copy")
 		test, _ := cmd.Flags().GetBool("test")
 
-		if len(args) == 0 {
-			fmt.Println("Index argument required. Use `gokp favorites list` to see all favorites.")
-			os.Exit(0)
-		}
-
 		index, err := strconv.Atoi(args[0])
 		if err != nil {
-			fmt.Printf("Invalid index: %s\n", args[0])
-			return
+			return newError(ErrUsage, "invalid index: %s", args[0])
 		}
-		showFavoriteEntry(index, showPassword, copyToClipboard, test)
+		return showFavoriteEntry(index, showPassword, copyToClipboard, test)
 	},
 }
 
 var favoritesCmd = &cobra.Command{
-	Use:   "favorites [INDEX]",
-	Short: "Use and manage favorites entries",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Use:               "favorites [INDEX]",
+	ValidArgsFunction: completeFavoriteIndexes,
+	Short:             "Use and manage favorites entries",
+	Args:              cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		showPassword, _ := cmd.Flags().GetBool("password")
 		copyToClipboard, _ := cmd.Flags().GetBool("copy")
 		test, _ := cmd.Flags().GetBool("test")
 
-		if len(args) == 0 {
-			fmt.Println("Index argument required. Use `gokp favorites list` to see all favorites.")
-			os.Exit(0)
-		}
-
 		index, err := strconv.Atoi(args[0])
 		if err != nil {
-			fmt.Printf("Invalid index: %s\n", args[0])
-			return
+			return newError(ErrUsage, "invalid index: %s", args[0])
 		}
-		showFavoriteEntry(index, showPassword, copyToClipboard, test)
+		return showFavoriteEntry(index, showPassword, copyToClipboard, test)
 	},
 }
 
@@ -87,33 +76,34 @@ var favoritesList = &cobra.Command{
 	Use:   "list",
 	Short: "List favorites from external Keepass databases",
 	// Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		test, _ := cmd.Flags().GetBool("test")
 
 		_, _, gokpKDBX := pathSelection(test)
 
-		secret, err := getGoKPPassword()
+		secret, err := getGoKPPassword(gokpKDBX)
 		if err != nil {
-			log.Fatalf("Failed to get GoKP password: %v", err)
+			return fmt.Errorf("failed to get GoKP password: %w", err)
 		}
 
 		db, err := openKeepassDB(gokpKDBX, secret)
 		if err != nil {
-			log.Fatalf("Failed to open Keepass database: %v", err)
+			return fmt.Errorf("failed to open Keepass database: %w", err)
 		}
 
 		var favGroupIndex int
 		for i := range db.Content.Root.Groups {
-			if db.Content.Root.Groups[i].Name == "favorites" {
+			if db.Content.Root.Groups[i].Name == favoritesGroupName {
 				favGroupIndex = i
 				break
 			}
 		}
 
 		for _, entry := range db.Content.Root.Groups[favGroupIndex].Entries {
-			index := entry.GetContent("Favorite Index")
+			index := entry.GetContent(attrFavoriteIndex)
 			printFavoritesResult(index, entry, "favorites")
 		}
+		return nil
 	},
 }
 
@@ -126,27 +116,40 @@ var favoritesList = &cobra.Command{
 // 	},
 // }
 
-func showFavoriteEntry(index int, showPassword, copyToClipboard bool, test bool) {
+func showFavoriteEntry(index int, showPassword, copyToClipboard bool, test bool) error {
 	_, _, gokpKDBX := pathSelection(test)
 
-	secret, err := getGoKPPassword()
+	secret, err := getGoKPPassword(gokpKDBX)
 	if err != nil {
-		log.Fatalf("Failed to get GoKP password: %v", err)
+		return fmt.Errorf("failed to get GoKP password: %w", err)
 	}
 
 	db, err := openKeepassDB(gokpKDBX, secret)
 	if err != nil {
-		log.Fatalf("Failed to open Keepass database: %v", err)
+		return fmt.Errorf("failed to open Keepass database: %w", err)
 	}
 
 	// Get the favorite entry password by index
 	entry := readFavoritesEntry(db, index)
 	if entry == nil {
-		fmt.Printf("No favorite found at index %d\n", index)
-		return
+		return newError(ErrNotFound, "no favorite found at index %d", index)
+	}
+
+	// Favorites added before field references were expanded may still hold them
+	password := entry.GetPassword()
+	if hasFieldRefs(password) {
+		resolved, err := newSecretResolver(db).favoriteField(index, "Password")
+		if err != nil {
+			fmt.Printf("Warning: Failed to expand field references: %v\n", err)
+		} else {
+			password = resolved
+		}
 	}
 
 	fmt.Printf("\n%s------ Entry -------%s\n", ColorBoldCyan, ColorReset)
+	if warning := expiryWarning(*entry); warning != "" {
+		fmt.Println(warning)
+	}
 	fmt.Printf("Favorite: %d\n", index)
 	fmt.Printf("Title:    %s\n", entry.GetTitle())
 	if entry.GetContent("UserName") != "" {
@@ -155,12 +158,15 @@ func showFavoriteEntry(index int, showPassword, copyToClipboard bool, test bool)
 	if entry.GetContent("URL") != "" {
 		fmt.Printf("URL:      %s\n", entry.GetContent("URL"))
 	}
+	if tags := entryTags(*entry); len(tags) > 0 {
+		fmt.Printf("Tags:     %s\n", strings.Join(tags, ", "))
+	}
 	if showPassword {
 		fmt.Printf("")
 		fmt.Printf("%s----- Password -----%s\n", ColorBoldCyan, ColorReset)
 
 		if showPassword {
-			fmt.Println(entry.GetPassword())
+			fmt.Println(password)
 		}
 	}
 	fmt.Printf("%s--------------------%s\n", ColorBoldCyan, ColorReset)
@@ -174,16 +180,19 @@ func showFavoriteEntry(index int, showPassword, copyToClipboard bool, test bool)
 		originalClipboard, _ := clipboard.ReadAll()
 
 		// Copy password to clipboard
-		err := clipboard.WriteAll(entry.GetPassword())
+		err := clipboard.WriteAll(password)
 		if err != nil {
-			fmt.Printf("Failed to copy password to clipboard: %v\n", err)
-			return
+			return fmt.Errorf("failed to copy password to clipboard: %w", err)
 		}
 
-		config := readConfig()
+		config, err := readConfig(test)
+		if err != nil {
+			return err
+		}
 		fmt.Printf("\nPassword for favorite #%d copied to clipboard\n", index)
-		showCountdownBarWithSignalHandling(config.ClipboardTimeout, originalClipboard, entry.GetPassword())
+		showCountdownBarWithSignalHandling(config.ClipboardTimeout, originalClipboard, password)
 	}
+	return nil
 }
 
 // Enhanced countdown with signal handling
@@ -274,10 +283,13 @@ func printFavoritesResult(count string, entry gokeepasslib.Entry, databaseName s
 	if url != "" {
 		fmt.Printf("URL:       %s\n", url)
 	}
+	if tags := entryTags(entry); len(tags) > 0 {
+		fmt.Printf("Tags:      %s\n", strings.Join(tags, ", "))
+	}
 
 	var firstPass bool = true
 	for _, value := range entry.Values {
-		if value.Key == "Database Source" || value.Key == "Database path" {
+		if value.Key == attrDatabaseSource || value.Key == attrDatabasePath {
 			if firstPass {
 				fmt.Println("Custom Attributes:")
 				firstPass = false
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// fieldRefPattern matches a KeePass placeholder such as {USERNAME}, {S:Field} or
+// {REF:P@I:46C9B1FFBD4ABC4BBB260C6190BAD20C}
+var fieldRefPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// fieldRefCodes maps the field codes of {REF:W@S:V} references to entry fields
+var fieldRefCodes = map[byte]string{
+	'T': "Title",
+	'U': "UserName",
+	'P': "Password",
+	'A': "URL",
+	'N': "Notes",
+}
+
+// maxFieldRefDepth stops runaway expansion of references that never repeat a field
+const maxFieldRefDepth = 20
+
+// fieldExpander expands field references and placeholders in entry values, following
+// references to other entries of the same database. Unknown placeholders are kept as is.
+type fieldExpander struct {
+	db *gokeepasslib.Database
+	// active holds the entry fields being expanded, to detect circular references
+	active map[string]bool
+}
+
+func newFieldExpander(db *gokeepasslib.Database) *fieldExpander {
+	return &fieldExpander{db: db, active: map[string]bool{}}
+}
+
+// resolvedEntry returns a copy of entry with the references in its values expanded.
+// Values that can't be expanded are kept as they are.
+func resolvedEntry(db *gokeepasslib.Database, entry gokeepasslib.Entry) gokeepasslib.Entry {
+	resolved := entry
+	resolved.Values = make([]gokeepasslib.ValueData, len(entry.Values))
+	for i, value := range entry.Values {
+		resolved.Values[i] = value
+		if !hasFieldRefs(value.Value.Content) {
+			continue
+		}
+		if expanded, err := newFieldExpander(db).field(&entry, value.Key); err == nil {
+			resolved.Values[i].Value.Content = expanded
+		}
+	}
+	return resolved
+}
+
+// field returns the expanded value of a field of entry, or "" if the entry lacks it
+func (x *fieldExpander) field(entry *gokeepasslib.Entry, key string) (string, error) {
+	raw, ok := "", false
+	for _, value := range entry.Values {
+		if strings.EqualFold(value.Key, key) {
+			raw, ok = value.Value.Content, true
+			break
+		}
+	}
+	if !ok || !hasFieldRefs(raw) {
+		return raw, nil
+	}
+
+	id := fmt.Sprintf("%x/%s", entry.UUID, strings.ToLower(key))
+	if x.active[id] {
+		return "", fmt.Errorf("circular field reference through '%s' of entry '%s'", key, entry.GetTitle())
+	}
+	if len(x.active) >= maxFieldRefDepth {
+		return "", fmt.Errorf("field references of entry '%s' nest more than %d deep", entry.GetTitle(), maxFieldRefDepth)
+	}
+	x.active[id] = true
+	defer delete(x.active, id)
+	return x.expand(entry, raw)
+}
+
+func (x *fieldExpander) expand(entry *gokeepasslib.Entry, value string) (string, error) {
+	var expanded strings.Builder
+	last := 0
+	for _, match := range fieldRefPattern.FindAllStringSubmatchIndex(value, -1) {
+		expanded.WriteString(value[last:match[0]])
+		last = match[1]
+
+		token := value[match[2]:match[3]]
+		replacement, ok, err := x.placeholder(entry, token)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			replacement = value[match[0]:match[1]]
+		}
+		expanded.WriteString(replacement)
+	}
+	expanded.WriteString(value[last:])
+	return expanded.String(), nil
+}
+
+// placeholder returns the value of a placeholder without its braces, and false if it is
+// not one gokp expands
+func (x *fieldExpander) placeholder(entry *gokeepasslib.Entry, token string) (string, bool, error) {
+	upper := strings.ToUpper(token)
+	switch {
+	case strings.HasPrefix(upper, "REF:"):
+		value, err := x.reference(token)
+		return value, err == nil, err
+	case strings.HasPrefix(upper, "S:"):
+		key := token[2:]
+		for _, value := range entry.Values {
+			if strings.EqualFold(value.Key, key) {
+				expanded, err := x.field(entry, value.Key)
+				return expanded, err == nil, err
+			}
+		}
+		return "", false, nil
+	}
+
+	for _, key := range []string{"Title", "UserName", "URL", "Password", "Notes"} {
+		if upper == strings.ToUpper(key) {
+			value, err := x.field(entry, key)
+			return value, err == nil, err
+		}
+	}
+	return "", false, nil
+}
+
+// reference resolves the body of a {REF:W@S:V} reference: field W of the first entry
+// whose field S contains V, or whose UUID is V when S is I
+func (x *fieldExpander) reference(token string) (string, error) {
+	spec := token[len("REF:"):]
+	if len(spec) < 4 || spec[1] != '@' || spec[3] != ':' {
+		return "", fmt.Errorf("invalid field reference '{%s}'", token)
+	}
+	wanted, searchIn, search := spec[0]&^0x20, spec[2]&^0x20, spec[4:]
+
+	target := x.findReferenced(searchIn, search)
+	if target == nil {
+		return "", newError(ErrNotFound, "field reference '{%s}' matches no entry", token)
+	}
+	if wanted == 'I' {
+		return strings.ToUpper(fmt.Sprintf("%x", target.UUID)), nil
+	}
+	field, ok := fieldRefCodes[wanted]
+	if !ok {
+		return "", fmt.Errorf("field reference '{%s}' asks for unknown field '%c'", token, spec[0])
+	}
+	return x.field(target, field)
+}
+
+func (x *fieldExpander) findReferenced(searchIn byte, search string) *gokeepasslib.Entry {
+	var found *gokeepasslib.Entry
+	forEachEntry(x.db.Content.Root.Groups, nil, func(_ []string, entry *gokeepasslib.Entry) {
+		if found != nil {
+			return
+		}
+		switch searchIn {
+		case 'I':
+			if strings.EqualFold(fmt.Sprintf("%x", entry.UUID), search) {
+				found = entry
+			}
+		case 'O':
+			for _, value := range entry.Values {
+				if !isStandardField(value.Key) && containsFold(value.Value.Content, search) {
+					found = entry
+					return
+				}
+			}
+		default:
+			if field, ok := fieldRefCodes[searchIn]; ok && containsFold(getEntryValue(*entry, field), search) {
+				found = entry
+			}
+		}
+	})
+	return found
+}
+
+func isStandardField(key string) bool {
+	for _, field := range fieldRefCodes {
+		if field == key {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// hasFieldRefs reports whether a value may hold references or placeholders
+func hasFieldRefs(value string) bool {
+	return fieldRefPattern.MatchString(value)
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+	"golang.org/x/term"
+)
+
+func init() {
+	rootCmd.AddCommand(gitCredentialCmd)
+}
+
+var gitCredentialCmd = &cobra.Command{
+	Use:   "git-credential get|store|erase",
+	Short: "Git credential helper backed by the external databases",
+	Long: `Git credential helper backed by the external databases.
+
+Speaks the git credential helper protocol on stdin/stdout. For 'get', the
+protocol, host and (with credential.useHttpPath) path git asks for are matched
+against the URL field of entries in all registered databases; the most specific
+match wins. 'store' and 'erase' are accepted but do nothing, since credentials
+are managed in KeePass.
+
+The gokp password is read from the OS keystore (see 'gokp auth login'), or
+prompted for on the controlling terminal.
+
+Setup:
+  git config --global credential.helper "gokp git-credential"
+  git config --global credential.useHttpPath true   # optional, match on repository path`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"get", "store", "erase"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		request, err := readGitCredentialRequest(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read credential request: %w", err)
+		}
+
+		switch args[0] {
+		case "get":
+		case "store", "erase":
+			return nil
+		default:
+			return newError(ErrUsage, "unknown operation: %s", args[0])
+		}
+
+		if request["host"] == "" {
+			return nil
+		}
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPasswordFromTTY()
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		var best *gokeepasslib.Entry
+		bestScore := 0
+		for _, external := range openExternalDatabases(gokpDB, "", os.Stderr) {
+			forEachEntry(external.DB.Content.Root.Groups, nil, func(_ []string, entry *gokeepasslib.Entry) {
+				resolved := resolvedEntry(external.DB, *entry)
+				if score := gitCredentialScore(resolved, request); score > bestScore {
+					best, bestScore = &resolved, score
+				}
+			})
+		}
+
+		// No output tells git to try the next helper or prompt
+		if best == nil {
+			return nil
+		}
+		fmt.Printf("username=%s\n", getEntryValue(*best, "UserName"))
+		fmt.Printf("password=%s\n", best.GetPassword())
+		return nil
+	},
+}
+
+// readGitCredentialRequest reads key=value lines until a blank line or EOF
+func readGitCredentialRequest(r io.Reader) (map[string]string, error) {
+	request := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		request[key] = value
+	}
+	return request, scanner.Err()
+}
+
+// gitCredentialScore rates how well the entry URL matches the credential request.
+// Zero means no match; the same host and port score 2, an entry URL without a port
+// matching the hostname scores 1, plus 2 or 3 for a path prefix or exact path match.
+func gitCredentialScore(entry gokeepasslib.Entry, request map[string]string) int {
+	entryURL := getEntryValue(entry, "URL")
+	if entryURL == "" {
+		return 0
+	}
+	if !strings.Contains(entryURL, "://") {
+		entryURL = request["protocol"] + "://" + entryURL
+	}
+	u, err := url.Parse(entryURL)
+	if err != nil || u.Host == "" {
+		return 0
+	}
+
+	if request["protocol"] != "" && !strings.EqualFold(u.Scheme, request["protocol"]) {
+		return 0
+	}
+	if username := request["username"]; username != "" && username != getEntryValue(entry, "UserName") {
+		return 0
+	}
+
+	score := 0
+	switch {
+	case strings.EqualFold(u.Host, request["host"]):
+		score = 2
+	case u.Port() == "" && strings.EqualFold(u.Hostname(), strings.Split(request["host"], ":")[0]):
+		score = 1
+	default:
+		return 0
+	}
+
+	entryPath := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	requestPath := strings.TrimSuffix(strings.Trim(request["path"], "/"), ".git")
+	switch {
+	case entryPath == "" || requestPath == "":
+	case entryPath == requestPath:
+		score += 3
+	case strings.HasPrefix(requestPath, entryPath+"/"):
+		score += 2
+	default:
+		return 0
+	}
+	return score
+}
+
+// getGoKPPasswordFromTTY reads the gokp password from the OS keystore, or prompts on the
+// controlling terminal when stdin is in use by another protocol
+func getGoKPPasswordFromTTY() (string, error) {
+	if passwordStdin {
+		return "", fmt.Errorf("--password-stdin can't be used here, git sends the credential request on stdin")
+	}
+	if secret, ok, err := passwordFromInput(); ok {
+		return secret, err
+	}
+	if secret, err := get_password("gokp", "local"); err == nil {
+		return secret, nil
+	}
+	if !interactive() {
+		return "", newError(ErrLocked, "the GoKP password is needed, run `gokp auth login` or pass it with --password-file or %s", passwordFDEnv)
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", newError(ErrLocked, "no password in the OS keystore and no terminal to prompt on, run `gokp auth login`")
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, "Enter admin password: ")
+	password, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password from terminal: %w", err)
+	}
+	if len(password) == 0 {
+		return "", fmt.Errorf("password is required")
+	}
+	return string(password), nil
+}
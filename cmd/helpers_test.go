@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/zalando/go-keyring"
+)
+
+const testGoKPPassword = "gokp-test-password"
+
+// newTestDatabase returns an unlocked KDBX 3.1 database with the given root groups. KDBX 3.1
+// keeps the key derivation cheap, so tests stay fast.
+func newTestDatabase(password string, groups ...gokeepasslib.Group) *gokeepasslib.Database {
+	db := gokeepasslib.NewDatabase(gokeepasslib.WithDatabaseKDBXVersion3())
+	db.Credentials = gokeepasslib.NewPasswordCredentials(password)
+	db.Content.Root = &gokeepasslib.RootData{Groups: groups}
+	return db
+}
+
+// newTestEntry returns an entry with the given fields, protecting the password
+func newTestEntry(fields ...string) gokeepasslib.Entry {
+	entry := gokeepasslib.NewEntry()
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == "Password" {
+			entry.Values = append(entry.Values, mkProtectedValue(fields[i], fields[i+1]))
+		} else {
+			entry.Values = append(entry.Values, mkValue(fields[i], fields[i+1]))
+		}
+	}
+	return entry
+}
+
+func newTestGroup(name string, entries ...gokeepasslib.Entry) gokeepasslib.Group {
+	group := gokeepasslib.NewGroup()
+	group.Name = name
+	group.Entries = entries
+	return group
+}
+
+// setupTestHome points the home folder at a temporary directory holding an empty GoKP
+// database, with its password in a mocked keystore. It returns the GoKP database path.
+func setupTestHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	keyring.MockInit()
+	if err := keyring.Set("gokp", "local", testGoKPPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	gokpFolder, _, gokpKDBX := pathSelection(false)
+	if err := os.MkdirAll(gokpFolder, gokpDirMode); err != nil {
+		t.Fatal(err)
+	}
+	db := newTestDatabase(testGoKPPassword, newTestGroup(databasesGroupName), newTestGroup(favoritesGroupName))
+	setDBSchemaVersion(db, schemaVersion)
+	if err := saveKeepassDB(db, gokpKDBX); err != nil {
+		t.Fatal(err)
+	}
+	return gokpKDBX
+}
+
+// registerTestDatabase saves db to the test home and registers it in the GoKP database
+func registerTestDatabase(t *testing.T, gokpKDBX, name, password string, db *gokeepasslib.Database) string {
+	t.Helper()
+	path := filepath.Join(filepath.Dir(gokpKDBX), name+".kdbx")
+	if err := saveExternalKeepassDB(db, path); err != nil {
+		t.Fatal(err)
+	}
+	gokpDB := openTestGoKP(t, gokpKDBX)
+	addGoKPEntryToGroup(gokpDB, databasesGroupName, name, password, path, "")
+	if err := saveKeepassDB(gokpDB, gokpKDBX); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openTestGoKP(t *testing.T, gokpKDBX string) *gokeepasslib.Database {
+	t.Helper()
+	db, err := openKeepassDB(gokpKDBX, testGoKPPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// runGoKP runs a gokp command line, resetting all flags afterwards so commands can run
+// again in the same test binary
+func runGoKP(t *testing.T, args ...string) error {
+	t.Helper()
+	defer resetFlags(rootCmd)
+	suppliedPassword = nil
+	rootCmd.SetArgs(args)
+	_, err := rootCmd.ExecuteC()
+	return err
+}
+
+func resetFlags(cmd *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		if flag.Changed {
+			if value, ok := flag.Value.(pflag.SliceValue); ok {
+				value.Replace(nil)
+			} else {
+				flag.Value.Set(flag.DefValue)
+			}
+			flag.Changed = false
+		}
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// captureStdout returns what fn writes to the standard output
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureOutput(t, &os.Stdout, fn)
+}
+
+// captureOutput returns what fn writes to stream, which is os.Stdout or os.Stderr
+func captureOutput(t *testing.T, stream **os.File, fn func()) string {
+	t.Helper()
+	file, err := os.Create(filepath.Join(t.TempDir(), "output"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	saved := *stream
+	*stream = file
+	defer func() { *stream = saved }()
+
+	fn()
+	output, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(output)
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().Int("show", 0, "Show the fields of version N")
+	historyCmd.Flags().Int("restore", 0, "Restore version N, keeping the current version in the history")
+	historyCmd.Flags().BoolP("password", "p", false, "Print passwords and protected fields instead of masking them")
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history ENTRY",
+	Short: "List, show and restore previous versions of an entry",
+	Long: `List the previous versions of an entry kept in its history, newest first.
+
+Version 0 is the current entry, version 1 the one it replaced, and so on. Each version
+lists the fields that changed in the version after it. Protected fields are masked
+unless -p is given.
+
+--restore N makes version N the current entry again and writes the external database.
+The current version is kept in the history, so a restore can be undone.
+
+Entries:
+  DATABASE/[GROUP/...]TITLE   Entry in a registered database
+  fav:INDEX                   Favorite by index, following it to its source entry
+
+Examples:
+  gokp history prod/web
+  gokp history prod/web --show 1 -p
+  gokp history fav:2 --restore 1`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		show, _ := cmd.Flags().GetInt("show")
+		restore, _ := cmd.Flags().GetInt("restore")
+		showPassword, _ := cmd.Flags().GetBool("password")
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		resolver := newSecretResolver(gokpDB)
+		db, entry, err := resolver.resolveEntry(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to find entry: %w", err)
+		}
+		versions := entryVersions(entry)
+
+		for _, n := range []int{show, restore} {
+			if n < 0 || n >= len(versions) {
+				return newError(ErrNotFound, "entry '%s' has no version %d, it has %d previous version(s)", entry.GetTitle(), n, len(versions)-1)
+			}
+		}
+
+		switch {
+		case restore > 0:
+			dbPath, err := resolver.databasePath(db)
+			if err != nil {
+				return fmt.Errorf("failed to find database path: %w", err)
+			}
+			restoreEntryVersion(db, entry, versions[restore])
+			if err := saveExternalKeepassDB(db, dbPath); err != nil {
+				return fmt.Errorf("failed to save database: %w", err)
+			}
+			fmt.Printf("Restored version %d of '%s' from %s\n", restore, entry.GetTitle(), versionTime(versions[restore]))
+
+			// Keep favorites of this entry in step with the restored version
+			name := resolver.databaseName(db)
+			if _, changed := refreshFavorites(resolver, map[string]bool{name: true}); changed {
+				if err := saveKeepassDB(gokpDB, gokpKDBX); err != nil {
+					return fmt.Errorf("failed to update favorites: %w", err)
+				}
+			}
+		case cmd.Flags().Changed("show"):
+			printEntryVersion(versions[show], show, showPassword)
+		default:
+			fmt.Printf("Versions of '%s':\n", entry.GetTitle())
+			for i, version := range versions {
+				label := "current"
+				if i > 0 {
+					label = "changed: " + strings.Join(changedFields(version, versions[i-1]), ", ")
+				}
+				fmt.Printf("  [%s%d%s] %s  %s\n", ColorBoldGreen, i, ColorReset, versionTime(version), label)
+			}
+			if len(versions) == 1 {
+				fmt.Println("No previous versions.")
+			}
+		}
+		return nil
+	},
+}
+
+// entryVersions returns the entry followed by its history versions, newest first
+func entryVersions(entry *gokeepasslib.Entry) []gokeepasslib.Entry {
+	var history []gokeepasslib.Entry
+	for _, h := range entry.Histories {
+		history = append(history, h.Entries...)
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return timeOf(history[i].Times.LastModificationTime).After(timeOf(history[j].Times.LastModificationTime))
+	})
+	return append([]gokeepasslib.Entry{withoutHistory(*entry)}, history...)
+}
+
+func versionTime(version gokeepasslib.Entry) string {
+	modified := timeOf(version.Times.LastModificationTime)
+	if modified.IsZero() {
+		return "unknown time       "
+	}
+	return modified.Local().Format("2006-01-02 15:04:05")
+}
+
+// changedFields lists the fields that differ between a version and the one replacing it
+func changedFields(version, next gokeepasslib.Entry) []string {
+	var changed []string
+	seen := map[string]bool{}
+	for _, values := range [][]gokeepasslib.ValueData{version.Values, next.Values} {
+		for _, value := range values {
+			if seen[value.Key] {
+				continue
+			}
+			seen[value.Key] = true
+			if getEntryValue(version, value.Key) != getEntryValue(next, value.Key) {
+				changed = append(changed, value.Key)
+			}
+		}
+	}
+	if strings.Join(attachmentNames(version), "\x00") != strings.Join(attachmentNames(next), "\x00") {
+		changed = append(changed, "Attachments")
+	}
+	if version.Tags != next.Tags {
+		changed = append(changed, "Tags")
+	}
+	if len(changed) == 0 {
+		changed = append(changed, "no fields")
+	}
+	return changed
+}
+
+func printEntryVersion(version gokeepasslib.Entry, n int, showPassword bool) {
+	fmt.Printf("\n%s----- Version %d -----%s\n", ColorBoldCyan, n, ColorReset)
+	fmt.Printf("Modified:  %s\n", versionTime(version))
+	for _, value := range version.Values {
+		content := value.Value.Content
+		if (value.Value.Protected.Bool || value.Key == "Password") && !showPassword && content != "" {
+			content = "********"
+		}
+		fmt.Printf("%-10s %s\n", value.Key+":", content)
+	}
+	if len(version.Binaries) > 0 {
+		fmt.Printf("%-10s %s\n", "Attached:", strings.Join(attachmentNames(version), ", "))
+	}
+	if tags := entryTags(version); len(tags) > 0 {
+		fmt.Printf("%-10s %s\n", "Tags:", strings.Join(tags, ", "))
+	}
+	fmt.Printf("%s--------------------%s\n", ColorBoldCyan, ColorReset)
+}
+
+// pushEntryHistory adds the current state of an entry to its history before it is changed,
+// dropping the oldest versions beyond the database's history limit
+func pushEntryHistory(db *gokeepasslib.Database, entry *gokeepasslib.Entry) {
+	version := withoutHistory(*entry)
+	version.Values = append([]gokeepasslib.ValueData{}, entry.Values...)
+	version.Binaries = append([]gokeepasslib.BinaryReference{}, entry.Binaries...)
+	if entry.Times.LastModificationTime != nil {
+		modified := *entry.Times.LastModificationTime
+		version.Times.LastModificationTime = &modified
+	}
+
+	if len(entry.Histories) == 0 {
+		entry.Histories = []gokeepasslib.History{{}}
+	}
+	history := &entry.Histories[len(entry.Histories)-1]
+	history.Entries = append(history.Entries, version)
+
+	if db.Content.Meta != nil && db.Content.Meta.HistoryMaxItems >= 0 {
+		if extra := len(history.Entries) - int(db.Content.Meta.HistoryMaxItems); extra > 0 {
+			history.Entries = history.Entries[extra:]
+		}
+	}
+}
+
+// restoreEntryVersion makes a history version the current entry, keeping the current
+// version in the history
+func restoreEntryVersion(db *gokeepasslib.Database, entry *gokeepasslib.Entry, version gokeepasslib.Entry) {
+	pushEntryHistory(db, entry)
+	entry.Values = append([]gokeepasslib.ValueData{}, version.Values...)
+	entry.Binaries = append([]gokeepasslib.BinaryReference{}, version.Binaries...)
+	entry.Tags = version.Tags
+	touchEntry(entry)
+}
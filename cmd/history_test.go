@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+func TestHistoryRestoreUpdatesFavorite(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+
+	old := newTestEntry("Title", "web", "UserName", "bob", "Password", "old-password")
+	old.Times.LastModificationTime = &w.TimeWrapper{Time: time.Now().Add(-time.Hour)}
+	current := newTestEntry("Title", "web", "UserName", "bob", "Password", "new-password")
+	current.UUID = old.UUID
+	current.Histories = []gokeepasslib.History{{Entries: []gokeepasslib.Entry{old}}}
+	registerTestDatabase(t, gokpKDBX, "prod", "prod-password", newTestDatabase("prod-password", newTestGroup("Root", current)))
+
+	gokpDB := openTestGoKP(t, gokpKDBX)
+	if err := addFavoriteEntryToGoKP(gokpDB, SearchResult{Entry: current, DatabaseName: "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveKeepassDB(gokpDB, gokpKDBX); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runGoKP(t, "history", "prod/Root/web", "--restore", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	favorites := FindRootGroupByName(openTestGoKP(t, gokpKDBX).Content.Root.Groups, favoritesGroupName)
+	if len(favorites.Entries) != 1 {
+		t.Fatalf("got %d favorites, want 1", len(favorites.Entries))
+	}
+	if got := favorites.Entries[0].GetPassword(); got != "old-password" {
+		t.Errorf("favorite password = %q, want %q", got, "old-password")
+	}
+}
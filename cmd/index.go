@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+// searchIndex holds the non-secret fields of every entry in an external database. Indexes
+// are stored as JSON in the GoKP database's custom data, so they are encrypted with it.
+type searchIndex struct {
+	Version int            `json:"version"`
+	Path    string         `json:"path"`
+	ModTime time.Time      `json:"mtime"`
+	Size    int64          `json:"size"`
+	Updated time.Time      `json:"updated"`
+	Entries []indexedEntry `json:"entries"`
+}
+
+type indexedEntry struct {
+	UUID        string         `json:"uuid"`
+	Title       string         `json:"title"`
+	UserName    string         `json:"username,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	URLs        []string       `json:"urls,omitempty"`
+	Notes       string         `json:"notes,omitempty"`
+	Fields      []indexedField `json:"fields,omitempty"`
+	Group       []string       `json:"group"`
+	Attachments []string       `json:"attachments,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Expires     *time.Time     `json:"expires,omitempty"`
+}
+
+// indexedField is a custom field of an indexed entry. Protected fields are indexed by name
+// only, as their values are secret.
+type indexedField struct {
+	Name      string `json:"name"`
+	Value     string `json:"value,omitempty"`
+	Protected bool   `json:"protected,omitempty"`
+}
+
+// searchIndexVersion is bumped when indexedEntry gains fields, so older indexes are rebuilt
+const searchIndexVersion = 6
+
+// DatabaseIndex is the search index of a registered database along with its registry details
+type DatabaseIndex struct {
+	Name  string
+	Path  string
+	Index *searchIndex
+}
+
+func searchIndexKey(name string) string {
+	return searchIndexKeyPrefix + name
+}
+
+// readSearchIndex returns the stored index of a database, or nil if there is none
+func readSearchIndex(gokpDB *gokeepasslib.Database, name string) *searchIndex {
+	if gokpDB.Content.Meta == nil {
+		return nil
+	}
+	for _, item := range gokpDB.Content.Meta.CustomData {
+		if item.Key != searchIndexKey(name) {
+			continue
+		}
+		index := &searchIndex{}
+		if err := json.Unmarshal([]byte(item.Value), index); err != nil {
+			return nil
+		}
+		return index
+	}
+	return nil
+}
+
+func writeSearchIndex(gokpDB *gokeepasslib.Database, name string, index *searchIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if gokpDB.Content.Meta == nil {
+		gokpDB.Content.Meta = gokeepasslib.NewMetaData()
+	}
+	for i := range gokpDB.Content.Meta.CustomData {
+		if gokpDB.Content.Meta.CustomData[i].Key == searchIndexKey(name) {
+			gokpDB.Content.Meta.CustomData[i].Value = string(data)
+			return nil
+		}
+	}
+	gokpDB.Content.Meta.CustomData = append(gokpDB.Content.Meta.CustomData, gokeepasslib.CustomData{
+		Key:   searchIndexKey(name),
+		Value: string(data),
+	})
+	return nil
+}
+
+// buildSearchIndex indexes the non-secret fields of an opened external database. Fields are
+// stored as written, since expanding a reference could copy another entry's password.
+func buildSearchIndex(dbPath string, db *gokeepasslib.Database) (*searchIndex, error) {
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	index := &searchIndex{
+		Version: searchIndexVersion,
+		Path:    dbPath,
+		ModTime: info.ModTime(),
+		Size:    info.Size(),
+		Updated: time.Now(),
+		Entries: []indexedEntry{},
+	}
+	forEachEntry(db.Content.Root.Groups, nil, func(groupPath []string, entry *gokeepasslib.Entry) {
+		indexed := indexedEntry{
+			UUID:        fmt.Sprintf("%x", entry.UUID),
+			Title:       entry.GetTitle(),
+			UserName:    getEntryValue(*entry, "UserName"),
+			URL:         getEntryValue(*entry, "URL"),
+			URLs:        additionalURLs(*entry),
+			Notes:       getEntryValue(*entry, "Notes"),
+			Fields:      customFields(*entry),
+			Group:       groupPath,
+			Attachments: attachmentNames(*entry),
+			Tags:        entryTags(*entry),
+		}
+		if expires, ok := entryExpiry(*entry); ok {
+			indexed.Expires = &expires
+		}
+		index.Entries = append(index.Entries, indexed)
+	})
+	return index, nil
+}
+
+// customFields returns an entry's custom fields, leaving out the values of protected ones
+func customFields(entry gokeepasslib.Entry) []indexedField {
+	var fields []indexedField
+	for _, value := range entry.Values {
+		switch {
+		case value.Key == "Title", value.Key == "UserName", value.Key == "Password", value.Key == "URL", value.Key == "Notes":
+		case value.Key == additionalURLKey, strings.HasPrefix(value.Key, additionalURLKey+"_"):
+		case value.Value.Protected.Bool:
+			fields = append(fields, indexedField{Name: value.Key, Protected: true})
+		default:
+			fields = append(fields, indexedField{Name: value.Key, Value: value.Value.Content})
+		}
+	}
+	return fields
+}
+
+// pruneSearchIndexes removes the stored indexes of databases that are no longer registered,
+// e.g. after they were renamed or removed in the GoKP database, and reports whether any were
+func pruneSearchIndexes(gokpDB *gokeepasslib.Database) bool {
+	if gokpDB.Content.Meta == nil {
+		return false
+	}
+	registered := map[string]bool{}
+	if databasesGroup := FindRootGroupByName(gokpDB.Content.Root.Groups, databasesGroupName); databasesGroup != nil {
+		for _, dbEntry := range databasesGroup.Entries {
+			registered[dbEntry.GetTitle()] = true
+		}
+	}
+
+	var kept []gokeepasslib.CustomData
+	for _, item := range gokpDB.Content.Meta.CustomData {
+		if name, ok := strings.CutPrefix(item.Key, searchIndexKeyPrefix); ok && !registered[name] {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	pruned := len(kept) != len(gokpDB.Content.Meta.CustomData)
+	gokpDB.Content.Meta.CustomData = kept
+	return pruned
+}
+
+// isStale reports whether the database file changed since the index was built, or the
+// index was built by an older version of gokp
+func (index *searchIndex) isStale(dbPath string) bool {
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return true
+	}
+	return index.Version != searchIndexVersion || index.Path != dbPath || !index.ModTime.Equal(info.ModTime()) || index.Size != info.Size()
+}
+
+// loadSearchIndexes returns the indexes of every registered database, or only the one named
+// target if set, for a query. Indexes rebuilt because they were missing or stale are saved
+// to gokp.kdbx, so the database isn't decrypted again on the next query.
+func loadSearchIndexes(gokpDB *gokeepasslib.Database, gokpKDBX string, target string, warn io.Writer) []DatabaseIndex {
+	indexes, changed := refreshSearchIndexes(gokpDB, target, false, warn)
+	if changed {
+		if err := saveKeepassDB(gokpDB, gokpKDBX); err != nil {
+			fmt.Fprintf(warn, "Warning: Failed to save search index: %v\n", err)
+		}
+		gokpDB.UnlockProtectedEntries()
+	}
+	return indexes
+}
+
+// refreshSearchIndexes returns the indexes of every registered database, or only the one
+// named target if set. Missing or stale indexes are rebuilt by decrypting the database,
+// all of them when force is set, and indexes of unregistered databases are dropped. Only
+// the GoKP database in memory is updated; changed reports whether it needs saving.
+// Databases that can't be indexed are reported to warn and skipped.
+func refreshSearchIndexes(gokpDB *gokeepasslib.Database, target string, force bool, warn io.Writer) (indexes []DatabaseIndex, changed bool) {
+	databasesGroup := FindRootGroupByName(gokpDB.Content.Root.Groups, databasesGroupName)
+	if databasesGroup == nil {
+		return nil, false
+	}
+	changed = pruneSearchIndexes(gokpDB)
+
+	for _, dbEntry := range databasesGroup.Entries {
+		dbName := dbEntry.GetTitle()
+		if target != "" && dbName != target {
+			continue
+		}
+		dbPath := getEntryAttribute(&dbEntry, attrDatabasePath)
+
+		index := readSearchIndex(gokpDB, dbName)
+		if force || index == nil || index.isStale(dbPath) {
+			opened := openExternalDatabases(gokpDB, dbName, warn)
+			if len(opened) == 0 {
+				continue
+			}
+			built, err := buildSearchIndex(opened[0].Path, opened[0].DB)
+			if err != nil {
+				fmt.Fprintf(warn, "Warning: Failed to index database '%s': %v, skipping.\n", dbName, err)
+				continue
+			}
+			if err := writeSearchIndex(gokpDB, dbName, built); err != nil {
+				fmt.Fprintf(warn, "Warning: Failed to store index of database '%s': %v\n", dbName, err)
+			} else {
+				changed = true
+			}
+			index = built
+		}
+
+		indexes = append(indexes, DatabaseIndex{Name: dbName, Path: dbPath, Index: index})
+	}
+	return indexes, changed
+}
+
+// searchIndexEntries matches the query against the title, username, URL, notes, custom fields
+// and group path of indexed entries. targetGroup limits the search to one root group,
+// as in searchEntriesInGroup.
+func searchIndexEntries(index *searchIndex, query string, caseSensitive bool, exactMatch bool, targetGroup string) []indexedEntry {
+	if !caseSensitive {
+		query = strings.ToLower(query)
+	}
+
+	var results []indexedEntry
+	for _, entry := range index.Entries {
+		if targetGroup != "" && (len(entry.Group) == 0 || entry.Group[0] != targetGroup) {
+			continue
+		}
+
+		var custom []string
+		for _, field := range entry.Fields {
+			custom = append(custom, field.Name, field.Value)
+		}
+		fields := []string{entry.Title, entry.UserName, entry.URL, entry.Notes, strings.Join(custom, " "), strings.Join(entry.Group, "/")}
+		for i := range fields {
+			if !caseSensitive {
+				fields[i] = strings.ToLower(fields[i])
+			}
+		}
+
+		for i, field := range fields {
+			// Exact matches compare title, username, URL and notes only, like fuzzyMatch
+			if exactMatch && i < 4 && field == query ||
+				!exactMatch && (strings.Contains(field, query) || fuzzyStringMatch(field, query)) {
+				results = append(results, entry)
+				break
+			}
+		}
+	}
+	return results
+}
+
+// toEntry builds an entry holding only the indexed fields, for printing and selection
+func (e indexedEntry) toEntry() gokeepasslib.Entry {
+	entry := gokeepasslib.Entry{}
+	if uuid, err := hex.DecodeString(e.UUID); err == nil {
+		copy(entry.UUID[:], uuid)
+	}
+	entry.Values = append(entry.Values, mkValue("Title", e.Title))
+	if e.UserName != "" {
+		entry.Values = append(entry.Values, mkValue("UserName", e.UserName))
+	}
+	if e.URL != "" {
+		entry.Values = append(entry.Values, mkValue("URL", e.URL))
+	}
+	for i, additional := range e.URLs {
+		entry.Values = append(entry.Values, mkValue(fmt.Sprintf("%s_%d", additionalURLKey, i+1), additional))
+	}
+	if e.Notes != "" {
+		entry.Values = append(entry.Values, mkValue("Notes", e.Notes))
+	}
+	for _, field := range e.Fields {
+		if field.Protected {
+			entry.Values = append(entry.Values, mkProtectedValue(field.Name, ""))
+		} else {
+			entry.Values = append(entry.Values, mkValue(field.Name, field.Value))
+		}
+	}
+	for _, name := range e.Attachments {
+		entry.Binaries = append(entry.Binaries, gokeepasslib.BinaryReference{Name: name})
+	}
+	entry.Tags = strings.Join(e.Tags, ";")
+	if e.Expires != nil {
+		entry.Times.Expires = w.NewBoolWrapper(true)
+		entry.Times.ExpiryTime = &w.TimeWrapper{Formatted: true, Time: *e.Expires}
+	}
+	return entry
+}
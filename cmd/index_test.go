@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBuildSearchIndexKeepsSecretsOut(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+
+	target := newTestEntry("Title", "db", "Password", "s3cret")
+	leaky := newTestEntry(
+		"Title", "app",
+		"UserName", fmt.Sprintf("{REF:P@I:%x}", target.UUID),
+		"Notes", "rotate quarterly",
+		"Password", "app-password",
+	)
+	leaky.Values = append(leaky.Values, mkProtectedValue("API token", "token-value"), mkValue("Tenant", "acme-tenant"))
+	db := newTestDatabase("prod-password", newTestGroup("Root", target, leaky))
+	path := registerTestDatabase(t, gokpKDBX, "prod", "prod-password", db)
+
+	index, err := buildSearchIndex(path, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Sprintf("%+v", index.Entries)
+	for _, secret := range []string{"s3cret", "app-password", "token-value"} {
+		if strings.Contains(data, secret) {
+			t.Errorf("index contains secret %q: %s", secret, data)
+		}
+	}
+
+	tests := []struct {
+		query string
+		exact bool
+		want  int
+	}{
+		{"quarterly", false, 1},
+		{"api token", false, 1},
+		{"acme-tenant", false, 1},
+		{"rotate quarterly", true, 1},
+		{"s3cret", false, 0},
+	}
+	for _, tt := range tests {
+		if got := searchIndexEntries(index, tt.query, false, tt.exact, ""); len(got) != tt.want {
+			t.Errorf("searchIndexEntries(%q, exact=%v) = %d entries, want %d", tt.query, tt.exact, len(got), tt.want)
+		}
+	}
+}
+
+func TestRefreshSearchIndexesPrunesUnregisteredDatabases(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	db := newTestDatabase("prod-password", newTestGroup("Root", newTestEntry("Title", "web")))
+	registerTestDatabase(t, gokpKDBX, "prod", "prod-password", db)
+
+	gokpDB := openTestGoKP(t, gokpKDBX)
+	if err := writeSearchIndex(gokpDB, "renamed", &searchIndex{}); err != nil {
+		t.Fatal(err)
+	}
+	indexes, changed := refreshSearchIndexes(gokpDB, "", false, io.Discard)
+	if len(indexes) != 1 || indexes[0].Name != "prod" {
+		t.Fatalf("got indexes %+v, want only prod", indexes)
+	}
+	if !changed {
+		t.Error("refreshSearchIndexes did not report the changes")
+	}
+	if readSearchIndex(gokpDB, "renamed") != nil {
+		t.Error("index of unregistered database 'renamed' was kept")
+	}
+	if readSearchIndex(gokpDB, "prod") == nil {
+		t.Error("index of 'prod' was not stored")
+	}
+}
+
+func TestSearchSavesRebuiltIndexes(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	db := newTestDatabase("prod-password", newTestGroup("Root", newTestEntry("Title", "web")))
+	path := registerTestDatabase(t, gokpKDBX, "prod", "prod-password", db)
+
+	search := func() []byte {
+		t.Helper()
+		if err := runGoKP(t, "search", "web"); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(gokpKDBX)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	built := search()
+	if readSearchIndex(openTestGoKP(t, gokpKDBX), "prod") == nil {
+		t.Fatal("the index built by the search was not saved")
+	}
+	if again := search(); !bytes.Equal(built, again) {
+		t.Error("search rewrote gokp.kdbx although the index was up to date")
+	}
+
+	db.Content.Root.Groups[0].Entries = append(db.Content.Root.Groups[0].Entries, newTestEntry("Title", "webmail"))
+	if err := saveExternalKeepassDB(db, path); err != nil {
+		t.Fatal(err)
+	}
+	search()
+	if index := readSearchIndex(openTestGoKP(t, gokpKDBX), "prod"); index == nil || len(index.Entries) != 2 {
+		t.Errorf("the index rebuilt after the database changed was not saved: %+v", index)
+	}
+}
+
+func TestManageUpdateFailsWithoutIndexedDatabases(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	path := registerTestDatabase(t, gokpKDBX, "prod", "prod-password", newTestDatabase("prod-password", newTestGroup("Root")))
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGoKP(t, "manage", "update"); !errors.Is(err, ErrNoDatabases) {
+		t.Errorf("got error %v, want ErrNoDatabases", err)
+	}
+}
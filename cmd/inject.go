@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(injectCmd)
+	injectCmd.Flags().StringP("input", "i", "", "Template file to render (required)")
+	injectCmd.Flags().StringP("output", "o", "", "File to write the rendered output to (created 0600)")
+	injectCmd.Flags().Bool("check", false, "Validate every secret reference without writing output")
+	injectCmd.MarkFlagRequired("input")
+}
+
+var injectCmd = &cobra.Command{
+	Use:   "inject -i TEMPLATE -o OUTPUT",
+	Short: "Render a template with secrets from the external databases",
+	Long: `Render a Go text/template with secrets from the external databases.
+
+Template functions:
+  {{ gokp "DATABASE/[GROUP/...]TITLE" }}            Password of an entry
+  {{ gokp "DATABASE/[GROUP/...]TITLE" "FIELD" }}    Any field of an entry
+  {{ fav INDEX }}                                  Password of a favorite
+  {{ fav INDEX "FIELD" }}                          Any field of a favorite
+
+An entry with exactly that TITLE wins; otherwise TITLE matches titles containing it,
+ignoring case. A reference matching several entries fails with the candidates listed.
+
+The output file is written with 0600 permissions. With --check every reference is
+resolved and reported, but nothing is written.
+
+Examples:
+  gokp inject -i netrc.tmpl -o ~/.netrc
+  gokp inject -i app.yaml.tmpl --check`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input, _ := cmd.Flags().GetString("input")
+		output, _ := cmd.Flags().GetString("output")
+		check, _ := cmd.Flags().GetBool("check")
+
+		if output == "" && !check {
+			return newError(ErrUsage, "please provide an output file using --output, or use --check")
+		}
+
+		text, err := os.ReadFile(input)
+		if err != nil {
+			return fmt.Errorf("error reading template: %w", err)
+		}
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		renderer := &templateRenderer{resolver: newSecretResolver(gokpDB), collect: check}
+		var rendered bytes.Buffer
+		if err := renderer.render(input, string(text), &rendered); err != nil {
+			return fmt.Errorf("failed to render template: %w", err)
+		}
+
+		if check {
+			for _, ref := range renderer.resolved {
+				fmt.Printf("[%sOK%s]   %s\n", ColorBoldGreen, ColorReset, ref)
+			}
+			for _, err := range renderer.errors {
+				fmt.Printf("[%sFAIL%s] %v\n", ColorBoldRed, ColorReset, err)
+			}
+			fmt.Printf("\n%d of %d reference(s) resolved.\n", len(renderer.resolved), len(renderer.resolved)+len(renderer.errors))
+			if len(renderer.errors) > 0 {
+				return fmt.Errorf("%d reference(s) failed to resolve", len(renderer.errors))
+			}
+			return nil
+		}
+
+		if err := writeSecretFile(output, rendered.Bytes()); err != nil {
+			return fmt.Errorf("error writing output: %w", err)
+		}
+		fmt.Printf("Rendered %s to %s\n", input, output)
+		return nil
+	},
+}
+
+// templateRenderer executes templates with the gokp and fav functions. When collect is
+// set, resolution errors are recorded instead of aborting so every reference is checked.
+type templateRenderer struct {
+	resolver *secretResolver
+	collect  bool
+	resolved []string
+	errors   []error
+}
+
+func (t *templateRenderer) render(name, text string, out io.Writer) error {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"gokp": t.gokp,
+		"fav":  t.fav,
+	}).Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(out, nil)
+}
+
+func (t *templateRenderer) gokp(entry string, field ...string) (string, error) {
+	if len(field) > 1 {
+		return "", fmt.Errorf("gokp takes an entry and at most one field")
+	}
+	ref := entry
+	if len(field) == 1 {
+		ref += "#" + field[0]
+	}
+	return t.lookup(ref)
+}
+
+func (t *templateRenderer) fav(index int, field ...string) (string, error) {
+	if len(field) > 1 {
+		return "", fmt.Errorf("fav takes an index and at most one field")
+	}
+	ref := fmt.Sprintf("fav:%d", index)
+	if len(field) == 1 {
+		ref += "#" + field[0]
+	}
+	return t.lookup(ref)
+}
+
+func (t *templateRenderer) lookup(ref string) (string, error) {
+	value, err := t.resolver.resolve(ref)
+	if err != nil {
+		if t.collect {
+			t.errors = append(t.errors, err)
+			return "", nil
+		}
+		return "", err
+	}
+	if t.collect {
+		t.resolved = append(t.resolved, ref)
+	}
+	return value, nil
+}
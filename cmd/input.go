@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&noInput, "no-input", false, "Never prompt: fail with exit status 3 where input would be needed, or 7 for the GoKP password (also GOKP_NONINTERACTIVE=1)")
+	rootCmd.PersistentFlags().BoolVar(&passwordStdin, "password-stdin", false, "Read the GoKP password from stdin")
+	rootCmd.PersistentFlags().StringVar(&passwordFile, "password-file", "", "Read the GoKP password from a file only its owner can read")
+}
+
+// exitInputRequired is the exit status when a prompt is needed in non-interactive mode
+const exitInputRequired = 3
+
+// passwordFDEnv names a file descriptor to read the GoKP password from, e.g. GOKP_PASSWORD_FD=3
+// with `3<<<"$PASSWORD"`, which keeps the password out of the environment and process list
+const passwordFDEnv = "GOKP_PASSWORD_FD"
+
+// nonInteractiveEnv disables prompts like --no-input when set to a true value
+const nonInteractiveEnv = "GOKP_NONINTERACTIVE"
+
+var (
+	noInput       bool
+	passwordStdin bool
+	passwordFile  string
+
+	// suppliedPassword caches the password read from stdin, a file or a descriptor, which
+	// can only be read once
+	suppliedPassword *string
+)
+
+// interactive reports whether gokp may prompt
+func interactive() bool {
+	if noInput {
+		return false
+	}
+	value := os.Getenv(nonInteractiveEnv)
+	enabled, err := strconv.ParseBool(value)
+	return value == "" || (err == nil && !enabled)
+}
+
+// requireInput returns an ErrInputRequired error when prompting is disabled. Call it
+// before every prompt, naming what the prompt asks for.
+func requireInput(what string) error {
+	if interactive() {
+		return nil
+	}
+	return newError(ErrInputRequired, "%s is needed, but prompts are disabled by --no-input or %s", what, nonInteractiveEnv)
+}
+
+// passwordFromInput returns the GoKP password given with --password-stdin, --password-file
+// or GOKP_PASSWORD_FD, and false if none of them is used
+func passwordFromInput() (string, bool, error) {
+	if suppliedPassword != nil {
+		return *suppliedPassword, true, nil
+	}
+
+	fd := os.Getenv(passwordFDEnv)
+	sources := 0
+	for _, used := range []bool{passwordStdin, passwordFile != "", fd != ""} {
+		if used {
+			sources++
+		}
+	}
+	switch {
+	case sources == 0:
+		return "", false, nil
+	case sources > 1:
+		return "", true, fmt.Errorf("use only one of --password-stdin, --password-file and %s", passwordFDEnv)
+	}
+
+	var password string
+	var err error
+	switch {
+	case passwordStdin:
+		password, err = readPassword(os.Stdin, "stdin")
+	case passwordFile != "":
+		password, err = readPasswordFile(passwordFile)
+	default:
+		password, err = readPasswordFD(fd)
+	}
+	if err != nil {
+		return "", true, err
+	}
+	if password == "" {
+		return "", true, fmt.Errorf("the supplied password is empty")
+	}
+	suppliedPassword = &password
+	return password, true, nil
+}
+
+// readPassword reads a password up to the end of its first line
+func readPassword(r io.Reader, source string) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password from %s: %w", source, err)
+	}
+	password, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSuffix(password, "\r"), nil
+}
+
+func readPasswordFile(path string) (string, error) {
+	if insecure, mode, err := isGroupOrWorldAccessible(path); err == nil && insecure {
+		return "", fmt.Errorf("refusing to read the password from %s: it is accessible by group or others (%04o), run `chmod 600 %s`", path, mode, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open password file: %w", err)
+	}
+	defer file.Close()
+	return readPassword(file, path)
+}
+
+func readPasswordFD(fd string) (string, error) {
+	n, err := strconv.Atoi(fd)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("%s must be a file descriptor number, not '%s'", passwordFDEnv, fd)
+	}
+	file := os.NewFile(uintptr(n), "password-fd")
+	if file == nil {
+		return "", fmt.Errorf("%s=%d is not an open file descriptor", passwordFDEnv, n)
+	}
+	defer file.Close()
+	return readPassword(file, fmt.Sprintf("file descriptor %d", n))
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// keePass1Signature is the version signature of KeePass 1.x .kdb files
+var keePass1Signature = [4]byte{0x65, 0xfb, 0x4b, 0xb5}
+
+// decodeKDBX decodes a KDBX file into db, classifying failures as ErrBadCredentials,
+// ErrUnsupported or ErrCorruptFile. usesKeyFile mentions the key file in wrong password
+// errors, as a wrong key file can't be told apart from a wrong password.
+func decodeKDBX(file *os.File, db *gokeepasslib.Database, usesKeyFile bool) (err error) {
+	if err := checkKDBXSignature(file); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	// The decoder slices the file content without bounds checks and panics on truncated files
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = newError(ErrCorruptFile, "the file is truncated or corrupted")
+		}
+	}()
+	return classifyDecodeError(gokeepasslib.NewDecoder(file).Decode(db), usesKeyFile)
+}
+
+// checkKDBXSignature reads the file signature, which the decoder doesn't check
+func checkKDBXSignature(r io.Reader) error {
+	var signature gokeepasslib.Signature
+	if err := binary.Read(r, binary.LittleEndian, &signature); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return newError(ErrCorruptFile, "the file is empty or truncated")
+		}
+		return err
+	}
+
+	switch {
+	case signature.BaseSignature != gokeepasslib.BaseSignature:
+		return newError(ErrUnsupported, "not a KeePass database")
+	case signature.SecondarySignature == keePass1Signature:
+		return newError(ErrUnsupported, "KeePass 1.x databases (.kdb) are not supported, convert it to KDBX with KeePass 2 or KeePassXC")
+	case signature.SecondarySignature != gokeepasslib.SecondarySignature:
+		return newError(ErrUnsupported, "unknown KeePass database format")
+	case signature.MajorVersion < 2 || signature.MajorVersion > 4:
+		return newError(ErrUnsupported, "KDBX version %d.%d is not supported, only KDBX 2 to 4 are", signature.MajorVersion, signature.MinorVersion)
+	}
+	return nil
+}
+
+// classifyDecodeError turns an error of the KDBX decoder into one of the sentinel kinds.
+// The decoder's own errors are unexported, so some are recognized by their messages.
+func classifyDecodeError(err error, usesKeyFile bool) error {
+	if err == nil {
+		return nil
+	}
+	var syntaxErr *xml.SyntaxError
+	message := err.Error()
+	switch {
+	case errors.Is(err, gokeepasslib.ErrInvalidDatabaseOrCredentials), strings.HasPrefix(message, "Wrong password?"):
+		// Header HMAC (KDBX 4) or stream start bytes (KDBX 3) don't match the credentials
+		if usesKeyFile {
+			return newError(ErrBadCredentials, "wrong password or key file")
+		}
+		return newError(ErrBadCredentials, "wrong password")
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return newError(ErrCorruptFile, "the file is truncated")
+	case errors.Is(err, gokeepasslib.ErrUnsupportedEncrypterType), errors.Is(err, gokeepasslib.ErrUnsupportedStreamType):
+		return newError(ErrUnsupported, "%v", err)
+	case strings.Contains(message, "HMAC"), strings.Contains(message, "Sha256 of header"), errors.As(err, &syntaxErr):
+		// Checksums after the credentials were verified, or the decrypted XML, are broken
+		return newError(ErrCorruptFile, "the file is corrupted: %v", err)
+	}
+	return err
+}
+
+// keyFileError explains why a key file could not be read
+func keyFileError(path string, err error) error {
+	if os.IsNotExist(err) {
+		return newError(ErrNotFound, "key file '%s' not found", path)
+	}
+	if os.IsPermission(err) {
+		return err
+	}
+	return newError(ErrBadKeyFile, "'%s' is not a valid KeePass key file: %v", path, err)
+}
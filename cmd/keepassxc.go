@@ -0,0 +1,405 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func init() {
+	manageDbsCmd.AddCommand(importKeePassXCCmd)
+	importKeePassXCCmd.Flags().String("config", "", "Path to keepassxc.ini (default: search the standard KeePassXC locations)")
+	importKeePassXCCmd.Flags().BoolP("dry-run", "n", false, "List the databases that would be imported without registering them")
+}
+
+var importKeePassXCCmd = &cobra.Command{
+	Use:   "import-keepassxc",
+	Short: "Register the databases KeePassXC recently opened",
+	Long: `Register the databases KeePassXC recently opened, along with their key files.
+
+Reads LastOpenedDatabases and LastKeyFiles from keepassxc.ini and registers each
+database in the GoKP database, prompting for its password. Databases whose path is
+already registered are skipped.
+
+Examples:
+  gokp manage import-keepassxc --dry-run
+  gokp manage import-keepassxc --config ~/.config/keepassxc/keepassxc.ini`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath, _ := cmd.Flags().GetString("config")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		var configPaths []string
+		if configPath != "" {
+			configPaths = []string{configPath}
+		} else {
+			configPaths = keePassXCConfigPaths()
+		}
+
+		candidates, err := readKeePassXCDatabases(configPaths)
+		if err != nil {
+			return fmt.Errorf("failed to read KeePassXC config: %w", err)
+		}
+		if len(candidates) == 0 {
+			fmt.Println("No recently opened databases found in KeePassXC config.")
+			return nil
+		}
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		db, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open Keepass database: %w", err)
+		}
+
+		imported := 0
+		for _, candidate := range candidates {
+			if name := registeredDatabaseName(db, candidate.Path); name != "" {
+				fmt.Printf("- %s: already registered as '%s', skipping.\n", candidate.Path, name)
+				continue
+			}
+			if _, err := os.Stat(candidate.Path); err != nil {
+				fmt.Printf("- %s: file not found, skipping.\n", candidate.Path)
+				continue
+			}
+
+			name := uniqueDatabaseName(db, candidate.Path)
+			if dryRun {
+				fmt.Printf("- %s: would register as '%s'", candidate.Path, name)
+				if candidate.KeyFile != "" {
+					fmt.Printf(" with key file %s", candidate.KeyFile)
+				}
+				fmt.Println()
+				continue
+			}
+
+			fmt.Printf("\nDatabase: %s\n", candidate.Path)
+			if candidate.KeyFile != "" {
+				fmt.Printf("Key file: %s\n", candidate.KeyFile)
+			}
+			password, err := promptPassword(fmt.Sprintf("Password for '%s' (leave empty to skip): ", name))
+			if err != nil {
+				return err
+			}
+			if password == "" {
+				fmt.Println("Skipped.")
+				continue
+			}
+
+			if _, err := openExternalKeepassDB(candidate.Path, password, candidate.KeyFile); err != nil {
+				fmt.Printf("Warning: Failed to open database '%s': %v, skipping.\n", name, err)
+				continue
+			}
+
+			addGoKPEntryToGroup(db, databasesGroupName, name, password, candidate.Path, candidate.KeyFile)
+			imported++
+			fmt.Printf("Registered '%s'.\n", name)
+		}
+
+		if dryRun || imported == 0 {
+			return nil
+		}
+
+		if err := saveKeepassDB(db, gokpKDBX); err != nil {
+			return fmt.Errorf("failed to save Keepass database: %w", err)
+		}
+		fmt.Printf("\nSuccessfully imported %d database(s) from KeePassXC.\n", imported)
+		return nil
+	},
+}
+
+// keePassXCDatabase is a database KeePassXC remembers, with the key file last used to open it
+type keePassXCDatabase struct {
+	Path    string
+	KeyFile string
+}
+
+// keePassXCConfigPaths returns the locations KeePassXC keeps its settings and state in.
+// Since 2.7 the recent database list lives in the cache directory.
+func keePassXCConfigPaths() []string {
+	var paths []string
+	if dir, err := os.UserCacheDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "keepassxc", "keepassxc.ini"), filepath.Join(dir, "KeePassXC", "keepassxc.ini"))
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "keepassxc", "keepassxc.ini"), filepath.Join(dir, "KeePassXC", "keepassxc.ini"))
+	}
+	return paths
+}
+
+// readKeePassXCDatabases collects the recently opened databases from every existing config file
+func readKeePassXCDatabases(configPaths []string) ([]keePassXCDatabase, error) {
+	var databases []keePassXCDatabase
+	seen := map[string]bool{}
+	found := false
+
+	for _, configPath := range configPaths {
+		values, err := readQtIniFile(configPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		found = true
+
+		keyFiles := map[string]string{}
+		if raw, ok := values["LastKeyFiles"]; ok {
+			keyFiles, err = decodeQtVariantStringHash(raw)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode LastKeyFiles in %s: %w", configPath, err)
+			}
+		}
+
+		for _, path := range parseQtIniValue(values["LastOpenedDatabases"]) {
+			if path == "" || seen[path] {
+				continue
+			}
+			seen[path] = true
+			databases = append(databases, keePassXCDatabase{Path: path, KeyFile: keyFiles[path]})
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no keepassxc.ini found in %s", strings.Join(configPaths, ", "))
+	}
+	return databases, nil
+}
+
+// registeredDatabaseName returns the name under which path is registered, or "" if it isn't
+func registeredDatabaseName(db *gokeepasslib.Database, path string) string {
+	databases := FindRootGroupByName(db.Content.Root.Groups, databasesGroupName)
+	if databases == nil {
+		return ""
+	}
+	for _, entry := range databases.Entries {
+		if sameFilePath(entry.GetContent(attrDatabasePath), path) {
+			return entry.GetTitle()
+		}
+	}
+	return ""
+}
+
+// uniqueDatabaseName derives a registry name from the database file name, adding a
+// numeric suffix when the name is already taken
+func uniqueDatabaseName(db *gokeepasslib.Database, path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name := base
+	for i := 2; readEntryFromGroup(db, databasesGroupName, name) != nil; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
+
+func sameFilePath(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// readQtIniFile reads the raw key/value pairs of a QSettings ini file, ignoring sections
+func readQtIniFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return values, scanner.Err()
+}
+
+// parseQtIniValue unescapes a QSettings ini value into its list elements.
+// Single values come back as a one-element list.
+func parseQtIniValue(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+
+	var values []string
+	var current strings.Builder
+	inQuotes := false
+	runes := []rune(raw)
+
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case ch == '"':
+			inQuotes = !inQuotes
+		case ch == ',' && !inQuotes:
+			values = append(values, current.String())
+			current.Reset()
+			for i+1 < len(runes) && runes[i+1] == ' ' {
+				i++
+			}
+		case ch == '\\' && i+1 < len(runes):
+			i++
+			switch esc := runes[i]; esc {
+			case 'a':
+				current.WriteRune('\a')
+			case 'b':
+				current.WriteRune('\b')
+			case 'f':
+				current.WriteRune('\f')
+			case 'n':
+				current.WriteRune('\n')
+			case 'r':
+				current.WriteRune('\r')
+			case 't':
+				current.WriteRune('\t')
+			case 'v':
+				current.WriteRune('\v')
+			case 'x':
+				j := i + 1
+				for j < len(runes) && strings.ContainsRune("0123456789abcdefABCDEF", runes[j]) {
+					j++
+				}
+				code, _ := strconv.ParseUint(string(runes[i+1:j]), 16, 32)
+				current.WriteRune(rune(code))
+				i = j - 1
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				j := i
+				for j < len(runes) && runes[j] >= '0' && runes[j] <= '7' {
+					j++
+				}
+				code, _ := strconv.ParseUint(string(runes[i:j]), 8, 32)
+				current.WriteRune(rune(code))
+				i = j - 1
+			default:
+				current.WriteRune(esc)
+			}
+		default:
+			current.WriteRune(ch)
+		}
+	}
+	values = append(values, current.String())
+
+	for i, value := range values {
+		if strings.HasPrefix(value, "@@") {
+			values[i] = value[1:]
+		}
+	}
+	return values
+}
+
+// Qt metatype ids used by the QDataStream encoding of QVariant
+const (
+	qtVariantMap  = 8
+	qtVariantHash = 28
+	qtString      = 10
+)
+
+// decodeQtVariantStringHash decodes a QSettings "@Variant(...)" value holding a
+// QVariantHash or QVariantMap of strings, as KeePassXC stores LastKeyFiles
+func decodeQtVariantStringHash(raw string) (map[string]string, error) {
+	values := parseQtIniValue(raw)
+	if len(values) != 1 || !strings.HasPrefix(values[0], "@Variant(") || !strings.HasSuffix(values[0], ")") {
+		return nil, fmt.Errorf("not a @Variant value")
+	}
+
+	runes := []rune(strings.TrimSuffix(strings.TrimPrefix(values[0], "@Variant("), ")"))
+	data := make([]byte, len(runes))
+	for i, r := range runes {
+		data[i] = byte(r)
+	}
+	stream := &qtDataStream{data: data}
+
+	typeID := stream.uint32()
+	stream.uint8() // null flag
+	if typeID != qtVariantHash && typeID != qtVariantMap {
+		return nil, fmt.Errorf("unexpected variant type %d", typeID)
+	}
+
+	result := map[string]string{}
+	count := stream.uint32()
+	for i := uint32(0); i < count && stream.err == nil; i++ {
+		key := stream.string()
+		valueType := stream.uint32()
+		stream.uint8() // null flag
+		if valueType != qtString {
+			return nil, fmt.Errorf("unexpected value type %d for '%s'", valueType, key)
+		}
+		result[key] = stream.string()
+	}
+	return result, stream.err
+}
+
+// qtDataStream reads big-endian QDataStream primitives, remembering the first error
+type qtDataStream struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (s *qtDataStream) take(n int) []byte {
+	if s.err != nil {
+		return nil
+	}
+	if s.pos+n > len(s.data) {
+		s.err = fmt.Errorf("truncated variant data")
+		return nil
+	}
+	b := s.data[s.pos : s.pos+n]
+	s.pos += n
+	return b
+}
+
+func (s *qtDataStream) uint8() uint8 {
+	if b := s.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (s *qtDataStream) uint32() uint32 {
+	if b := s.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// string reads a QString, stored as a byte length followed by UTF-16BE code units
+func (s *qtDataStream) string() string {
+	length := s.uint32()
+	if length == 0xFFFFFFFF {
+		return ""
+	}
+	b := s.take(int(length))
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestParseQtIniValue(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"", nil},
+		{"/home/me/db.kdbx", []string{"/home/me/db.kdbx"}},
+		{"a, b,c", []string{"a", "b", "c"}},
+		{`"a, b", c`, []string{"a, b", "c"}},
+		{`C:\\Users\\me\\db.kdbx`, []string{`C:\Users\me\db.kdbx`}},
+		{`say \"hi\"`, []string{`say "hi"`}},
+		{`tab\there\nnext`, []string{"tab\there\nnext"}},
+		{`\x41\x42g`, []string{"ABg"}},
+		{`\101\0`, []string{"A\x00"}},
+		{"@@Variant(x)", []string{"@Variant(x)"}},
+	}
+	for _, tt := range tests {
+		if got := parseQtIniValue(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseQtIniValue(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeQtVariantStringHash(t *testing.T) {
+	files := map[string]string{
+		"/home/me/Passwords.kdbx": "/home/me/keys/passwords.keyx",
+		"/srv/ä team/db.kdbx":     "",
+	}
+
+	truncated := qtIniEscape(qtVariantStringHash(qtVariantHash, files))
+	tests := []struct {
+		name string
+		raw  string
+		want map[string]string
+		err  bool
+	}{
+		{"hash", qtIniEscape(qtVariantStringHash(qtVariantHash, files)), files, false},
+		{"map", qtIniEscape(qtVariantStringHash(qtVariantMap, files)), files, false},
+		{"empty", qtIniEscape(qtVariantStringHash(qtVariantHash, nil)), map[string]string{}, false},
+		{"written by KeePassXC", `@Variant(\0\0\0\x1c\0\0\0\0\x1\0\0\0\xe\0/\0\x61\0.\0k\0\x64\0\x62\0x\0\0\0\n\0\0\0\0\f\0/\0\x61\0.\0k\0\x65\0y)`,
+			map[string]string{"/a.kdbx": "/a.key"}, false},
+		{"not a variant", "/home/me/db.kdbx", nil, true},
+		{"list", "@Variant(a), @Variant(b)", nil, true},
+		{"wrong variant type", qtIniEscape(qtVariantStringHash(qtString, nil)), nil, true},
+		{"truncated", truncated[:len(truncated)/2] + ")", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := decodeQtVariantStringHash(tt.raw)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: decodeQtVariantStringHash = %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: decodeQtVariantStringHash error = %v", tt.name, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: decodeQtVariantStringHash = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// qtVariantStringHash encodes a QVariant of the given type holding string values the way
+// QDataStream does
+func qtVariantStringHash(typeID uint32, values map[string]string) []byte {
+	var data []byte
+	appendString := func(s string) {
+		units := utf16.Encode([]rune(s))
+		data = binary.BigEndian.AppendUint32(data, uint32(len(units)*2))
+		for _, unit := range units {
+			data = binary.BigEndian.AppendUint16(data, unit)
+		}
+	}
+	data = binary.BigEndian.AppendUint32(data, typeID)
+	data = append(data, 0)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(values)))
+	for key, value := range values {
+		appendString(key)
+		data = binary.BigEndian.AppendUint32(data, qtString)
+		data = append(data, 0)
+		appendString(value)
+	}
+	return data
+}
+
+// qtIniEscape writes variant data as a QSettings ini value, escaping bytes like Qt does:
+// digits following an escape are escaped as well, so they aren't read as part of it
+func qtIniEscape(data []byte) string {
+	var b strings.Builder
+	b.WriteString("@Variant(")
+	escapeNext := false
+	for _, c := range data {
+		switch {
+		case c == 0:
+			b.WriteString(`\0`)
+			escapeNext = true
+		case c == '\\' || c == '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+			escapeNext = false
+		case c == '\n':
+			b.WriteString(`\n`)
+			escapeNext = false
+		case c >= 0x20 && c < 0x7f && !(escapeNext && strings.IndexByte("0123456789abcdefABCDEF", c) >= 0):
+			b.WriteByte(c)
+			escapeNext = false
+		default:
+			fmt.Fprintf(&b, `\x%x`, c)
+			escapeNext = true
+		}
+	}
+	b.WriteString(")")
+	return b.String()
+}
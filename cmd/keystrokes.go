@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keyTyper sends keystrokes to the focused window
+type keyTyper interface {
+	// typeText types text, waiting delay between characters
+	typeText(text string, delay time.Duration) error
+	// pressKey presses a key from autoTypeKeys or a letter or digit, holding down mods
+	pressKey(key string, mods []string) error
+	// activeWindow returns the title of the focused window, or "" if it can't be found
+	activeWindow() string
+}
+
+// defaultKeystrokeDelay is the delay between typed characters until a sequence sets one
+const defaultKeystrokeDelay = 10 * time.Millisecond
+
+// newKeyTyper returns the typer for a backend name. auto picks xdotool when an X11
+// display is available and ydotool otherwise.
+func newKeyTyper(backend string) (keyTyper, error) {
+	if backend == "auto" {
+		backend = "ydotool"
+		if _, err := exec.LookPath("xdotool"); err == nil && os.Getenv("DISPLAY") != "" {
+			backend = "xdotool"
+		}
+	}
+
+	switch backend {
+	case "xdotool", "ydotool":
+		path, err := exec.LookPath(backend)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not installed", backend)
+		}
+		if backend == "xdotool" {
+			return xdotoolTyper{path: path}, nil
+		}
+		return ydotoolTyper{path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend '%s', expected auto, xdotool or ydotool", backend)
+	}
+}
+
+// typeKeystrokes sends parsed keystrokes through a typer
+func typeKeystrokes(typer keyTyper, strokes []keystroke) error {
+	delay := defaultKeystrokeDelay
+	for _, stroke := range strokes {
+		var err error
+		switch stroke.kind {
+		case strokeText:
+			err = typer.typeText(stroke.text, delay)
+		case strokeKey:
+			err = typer.pressKey(stroke.text, stroke.mods)
+			time.Sleep(delay)
+		case strokePause:
+			time.Sleep(stroke.delay)
+		case strokeSetDelay:
+			delay = stroke.delay
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runKeystrokeTool runs a typing tool, passing text on stdin so it never shows up in the
+// process list
+func runKeystrokeTool(path string, stdin string, args ...string) error {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v %s", path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// xdotoolTyper types into X11 windows with xdotool
+type xdotoolTyper struct {
+	path string
+}
+
+func (x xdotoolTyper) typeText(text string, delay time.Duration) error {
+	return runKeystrokeTool(x.path, text, "type", "--delay", strconv.Itoa(int(delay.Milliseconds())), "--file", "-")
+}
+
+func (x xdotoolTyper) pressKey(key string, mods []string) error {
+	return runKeystrokeTool(x.path, "", "key", "--clearmodifiers", strings.Join(append(append([]string{}, mods...), key), "+"))
+}
+
+func (x xdotoolTyper) activeWindow() string {
+	output, err := exec.Command(x.path, "getactivewindow", "getwindowname").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// ydotoolTyper types through the kernel's uinput device with ydotool 1.x, which needs
+// the ydotoold daemon and works under Wayland and on the console
+type ydotoolTyper struct {
+	path string
+}
+
+func (y ydotoolTyper) typeText(text string, delay time.Duration) error {
+	return runKeystrokeTool(y.path, text, "type", "--key-delay", strconv.Itoa(int(delay.Milliseconds())), "--file", "-")
+}
+
+func (y ydotoolTyper) pressKey(key string, mods []string) error {
+	code, ok := linuxKeyCodes[key]
+	if !ok {
+		return fmt.Errorf("ydotool can't press key '%s'", key)
+	}
+	var down, up []string
+	for _, mod := range mods {
+		down = append(down, fmt.Sprintf("%d:1", linuxKeyCodes[mod]))
+		up = append([]string{fmt.Sprintf("%d:0", linuxKeyCodes[mod])}, up...)
+	}
+	events := append(append(down, fmt.Sprintf("%d:1", code), fmt.Sprintf("%d:0", code)), up...)
+	return runKeystrokeTool(y.path, "", append([]string{"key"}, events...)...)
+}
+
+func (y ydotoolTyper) activeWindow() string {
+	// uinput has no notion of windows
+	return ""
+}
+
+// linuxKeyCodes maps key names to the Linux input event codes ydotool expects, for a US layout
+var linuxKeyCodes = map[string]int{
+	"shift": 42, "ctrl": 29, "alt": 56,
+	"Tab": 15, "Return": 28, "space": 57, "BackSpace": 14, "Delete": 111, "Insert": 110,
+	"Home": 102, "End": 107, "Prior": 104, "Next": 109,
+	"Up": 103, "Down": 108, "Left": 105, "Right": 106,
+	"Escape": 1, "Caps_Lock": 58, "Num_Lock": 69, "Scroll_Lock": 70,
+	"Print": 99, "Break": 119, "Help": 138, "Super_L": 125, "Super_R": 126, "Menu": 127,
+	"KP_Add": 78, "KP_Subtract": 74, "KP_Multiply": 55, "KP_Divide": 98,
+	"KP_0": 82, "KP_1": 79, "KP_2": 80, "KP_3": 81, "KP_4": 75,
+	"KP_5": 76, "KP_6": 77, "KP_7": 71, "KP_8": 72, "KP_9": 73,
+	"F1": 59, "F2": 60, "F3": 61, "F4": 62, "F5": 63, "F6": 64, "F7": 65, "F8": 66,
+	"F9": 67, "F10": 68, "F11": 87, "F12": 88, "F13": 183, "F14": 184, "F15": 185, "F16": 186,
+	"1": 2, "2": 3, "3": 4, "4": 5, "5": 6, "6": 7, "7": 8, "8": 9, "9": 10, "0": 11,
+	"q": 16, "w": 17, "e": 18, "r": 19, "t": 20, "y": 21, "u": 22, "i": 23, "o": 24, "p": 25,
+	"a": 30, "s": 31, "d": 32, "f": 33, "g": 34, "h": 35, "j": 36, "k": 37, "l": 38,
+	"z": 44, "x": 45, "c": 46, "v": 47, "b": 48, "n": 49, "m": 50,
+}
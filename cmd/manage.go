@@ -2,14 +2,10 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"strings"
-	"syscall"
 
 	"github.com/spf13/cobra"
-	"github.com/tobischo/gokeepasslib/v3"
-	"golang.org/x/term"
 )
 
 func init() {
@@ -17,6 +13,7 @@ func init() {
 	manageDbsCmd.AddCommand(openCmd)
 	manageDbsCmd.AddCommand(addDbCmd)
 	manageDbsCmd.AddCommand(listDbsCmd)
+	manageDbsCmd.AddCommand(cacheExternalDBs)
 	var TestMode bool
 	var SetupEntry bool
 	openCmd.PersistentFlags().BoolVarP(&TestMode, "test", "t", false, "Run CLI command in test mode")
@@ -43,7 +40,7 @@ Examples:
   gokp manage add mydb --path /path/to/database.kdbx
   gokp manage add mydb --path /path/to/database.kdbx --key /path/to/keyfile.key`,
 	Args: cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		entry_name := args[0]
 
 		// Get path and key from command-line flags
@@ -53,39 +50,38 @@ Examples:
 
 		// Validate that the database file exists
 		if _, err := os.Stat(kdbx_path); os.IsNotExist(err) {
-			log.Fatalf("Database file does not exist: %s", kdbx_path)
+			return newError(ErrNotFound, "database file does not exist: %s", kdbx_path)
 		}
 
 		// Validate key file if provided
 		if kdbx_key != "" {
 			if _, err := os.Stat(kdbx_key); os.IsNotExist(err) {
-				log.Fatalf("Key file does not exist: %s", kdbx_key)
+				return newError(ErrNotFound, "key file does not exist: %s", kdbx_key)
 			}
 		}
 
 		_, _, gokpKDBX := pathSelection(false)
 
-		secret, err := getGoKPPassword()
+		secret, err := getGoKPPassword(gokpKDBX)
 		if err != nil {
-			log.Fatalf("Failed to get GoKP password: %v", err)
+			return fmt.Errorf("failed to get GoKP password: %w", err)
 		}
 
 		db, err := openKeepassDB(gokpKDBX, secret)
 		if err != nil {
-			log.Fatalf("Failed to open Keepass database: %v", err)
+			return fmt.Errorf("failed to open Keepass database: %w", err)
 		}
 
-		existingEntry := readEntryFromGroup(db, "databases", entry_name)
+		existingEntry := readEntryFromGroup(db, databasesGroupName, entry_name)
 		if existingEntry != nil {
-			fmt.Printf("\nERROR: Database entry by the name '%s' already exists.\n", entry_name)
-			os.Exit(0)
+			return newError(ErrExists, "database entry by the name '%s' already exists", entry_name)
 		}
 
-		addGoKPEntryToGroup(db, "databases", entry_name, kdbx_password, kdbx_path, kdbx_key)
+		addGoKPEntryToGroup(db, databasesGroupName, entry_name, kdbx_password, kdbx_path, kdbx_key)
 
 		err = saveKeepassDB(db, gokpKDBX)
 		if err != nil {
-			log.Fatalf("Failed to save Keepass database: %v", err)
+			return fmt.Errorf("failed to save Keepass database: %w", err)
 		} else {
 			fmt.Printf("\nSuccessfully added new database entry '%s' to the GoKP database.\n", entry_name)
 			fmt.Printf("Database path: %s\n", kdbx_path)
@@ -93,44 +89,47 @@ Examples:
 				fmt.Printf("Key file: %s\n", kdbx_key)
 			}
 		}
+		return nil
 	},
 }
 
 var listDbsCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all databases in the GoKP database",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		test, _ := cmd.Flags().GetBool("test")
 		_, _, gokpKDBX := pathSelection(test)
 
-		secret, err := getGoKPPassword()
+		secret, err := getGoKPPassword(gokpKDBX)
 		if err != nil {
-			log.Fatalf("Failed to get GoKP password: %v", err)
+			return fmt.Errorf("failed to get GoKP password: %w", err)
 		}
 
 		db, err := openKeepassDB(gokpKDBX, secret)
 		if err != nil {
-			log.Fatalf("Failed to open Keepass database: %v", err)
+			return fmt.Errorf("failed to open Keepass database: %w", err)
 		}
 
-		databases := FindRootGroupByName(db.Content.Root.Groups, "databases")
+		databases := FindRootGroupByName(db.Content.Root.Groups, databasesGroupName)
 		fmt.Println("Databases:")
 		for _, entry := range databases.Entries {
-			fmt.Printf("- %s\n    Path: %s\n", entry.GetTitle(), entry.GetContent("Database Path"))
-			if entry.GetContent("Key File Path") != "" {
-				fmt.Printf("    Key:  %s\n", entry.GetContent("Key File Path"))
+			fmt.Printf("- %s\n    Path: %s\n", entry.GetTitle(), entry.GetContent(attrDatabasePath))
+			if entry.GetContent(attrKeyFilePath) != "" {
+				fmt.Printf("    Key:  %s\n", entry.GetContent(attrKeyFilePath))
 			}
 		}
+		return nil
 	},
 }
 
 var openCmd = &cobra.Command{
-	Use:   "open [NAME]",
-	Short: "Open existing database",
-	Args:  cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Use:               "open [NAME]",
+	Short:             "Open existing database",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeFirstDatabaseName,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) > 1 {
-			log.Fatal("Too many arguments passed. Cancelled.")
+			return newError(ErrUsage, "too many arguments passed")
 		}
 		name := strings.Join(args, "")
 		println(name)
@@ -141,32 +140,16 @@ var openCmd = &cobra.Command{
 
 		_, _, gokpKDBX := pathSelection(test)
 
-		secret, err := get_password("gokp", "local")
+		secret, err := getGoKPPassword(gokpKDBX)
 		if err != nil {
-			fmt.Print("Enter admin password: ")
-			password, _ := term.ReadPassword(int(syscall.Stdin))
-			if string(password) == "" {
-				fmt.Println("\nPassword is required")
-				os.Exit(1)
-			}
-			fmt.Println()
-			secret = string(password)
+			return fmt.Errorf("failed to get GoKP password: %w", err)
 		}
 
-		file, err := os.Open(gokpKDBX)
-		if err != nil {
-			log.Fatal(err)
-		}
-		db := gokeepasslib.NewDatabase()
-		db.Credentials = gokeepasslib.NewPasswordCredentials(secret)
-		err = gokeepasslib.NewDecoder(file).Decode(db)
+		db, err := decodeKeepassDB(gokpKDBX, secret)
 		if err != nil {
-			println("\nWARNING: Unable to open gokeepass db. The password is likely incorrect.")
-			os.Exit(1)
+			return fmt.Errorf("unable to open gokeepass db: %w", err)
 		}
 
-		db.UnlockProtectedEntries()
-
 		// entry := gokeepasslib.NewEntry()
 		// entry.Values = append(entry.Values, mkValue("Title", name))
 		// // entry.Values = append(entry.Values, mkValue("UserName", name))
@@ -180,7 +163,7 @@ var openCmd = &cobra.Command{
 		// 		Groups: []gokeepasslib.Group{rootGroup},
 		// 	},
 		// }
-		databases := FindRootGroupByName(db.Content.Root.Groups, "databases")
+		databases := FindRootGroupByName(db.Content.Root.Groups, databasesGroupName)
 		fmt.Printf("\nFound group: %s\n", databases.Name)
 		fmt.Println("Databases:")
 		for _, entry := range databases.Entries {
@@ -206,13 +189,57 @@ var openCmd = &cobra.Command{
 
 		// println(passwordStr)
 		// createDB(gokpKDBX, passwordStr)
+		return nil
 	},
 }
 
-// var cacheExternalDBs = &cobra.Command{
-// 	Use:   "update",
-// 	Short: "'Cache' or update cache of external databases",
-// 	Args:  cobra.MinimumNArgs(1),
-// 	Run: func(cmd *cobra.Command, args []string) {
-// 	},
-// }
+var cacheExternalDBs = &cobra.Command{
+	Use:               "update [NAME...]",
+	ValidArgsFunction: completeDatabaseNames,
+	Short:             "Rebuild the search index of external databases",
+	Long: `Rebuild the encrypted search index of all external databases, or only the named ones.
+
+Searches rebuild and save the index of a database whose file changed; run this to index
+every database ahead of time, or after a rebuild failed.
+Indexes of databases that are no longer registered are removed.
+
+Examples:
+  gokp manage update
+  gokp manage update mydb`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		db, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open Keepass database: %w", err)
+		}
+
+		var indexes []DatabaseIndex
+		if len(args) == 0 {
+			indexes, _ = refreshSearchIndexes(db, "", true, os.Stdout)
+		}
+		for _, name := range args {
+			if readEntryFromGroup(db, databasesGroupName, name) == nil {
+				return newError(ErrNotFound, "database '%s' is not registered", name)
+			}
+			indexed, _ := refreshSearchIndexes(db, name, true, os.Stdout)
+			indexes = append(indexes, indexed...)
+		}
+
+		if len(indexes) == 0 {
+			return newError(ErrNoDatabases, "no accessible external databases found")
+		}
+		if err := saveKeepassDB(db, gokpKDBX); err != nil {
+			return fmt.Errorf("failed to save GoKP database: %w", err)
+		}
+		for _, indexed := range indexes {
+			fmt.Printf("Indexed %d entries in '%s'\n", len(indexed.Index.Entries), indexed.Name)
+		}
+		return nil
+	},
+}
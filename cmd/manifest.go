@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	exportCmd.Flags().Bool("no-secrets", false, "Confirm the export contains no passwords (required)")
+	exportCmd.Flags().StringP("output", "o", "", "Write the manifest to a file instead of stdout")
+	importCmd.Flags().StringP("input", "i", "", "Manifest file to import (required)")
+	importCmd.Flags().BoolP("dry-run", "n", false, "Show what would be imported without changing the GoKP database")
+	importCmd.MarkFlagRequired("input")
+}
+
+// Manifest is a portable, secret free description of a GoKP registry
+type Manifest struct {
+	SchemaVersion int                `json:"schema-version"`
+	Exported      string             `json:"exported"`
+	Databases     []ManifestDatabase `json:"databases"`
+	Favorites     []ManifestFavorite `json:"favorites"`
+}
+
+// ManifestDatabase is a registered external database. Paths under the home
+// directory are written as $HOME/... so they carry across machines.
+type ManifestDatabase struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	KeyFile string `json:"key-file,omitempty"`
+}
+
+// ManifestFavorite points at an entry in a registered database by UUID
+type ManifestFavorite struct {
+	Database string `json:"database"`
+	UUID     string `json:"uuid"`
+	Index    int    `json:"index"`
+	Alias    string `json:"alias"`
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export --no-secrets [-o FILE]",
+	Short: "Export registered databases and favorites as a JSON manifest",
+	Long: `Export registered databases and favorites as a JSON manifest.
+
+The manifest never contains passwords. Use 'gokp import' on another machine to
+rebuild the registry from it.
+
+Examples:
+  gokp export --no-secrets > gokp-manifest.json
+  gokp export --no-secrets -o gokp-manifest.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		noSecrets, _ := cmd.Flags().GetBool("no-secrets")
+		output, _ := cmd.Flags().GetString("output")
+
+		if !noSecrets {
+			return newError(ErrUsage, "exports never include passwords. Pass --no-secrets to confirm")
+		}
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		db, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open Keepass database: %w", err)
+		}
+
+		data, err := json.MarshalIndent(buildManifest(db), "", "    ")
+		if err != nil {
+			return fmt.Errorf("error marshalling manifest to JSON: %w", err)
+		}
+		data = append(data, '\n')
+
+		if output == "" {
+			os.Stdout.Write(data)
+			return nil
+		}
+		if err := writeSecretFile(output, data); err != nil {
+			return fmt.Errorf("error writing manifest: %w", err)
+		}
+		fmt.Printf("Manifest written to %s\n", output)
+		return nil
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import -i FILE",
+	Short: "Rebuild registered databases and favorites from a manifest",
+	Long: `Rebuild registered databases and favorites from a manifest created by 'gokp export'.
+
+Prompts for the password of each database that is not registered yet. Databases
+and favorites that already exist are skipped.
+
+Examples:
+  gokp import -i gokp-manifest.json
+  gokp import -i gokp-manifest.json --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input, _ := cmd.Flags().GetString("input")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		data, err := os.ReadFile(input)
+		if err != nil {
+			return fmt.Errorf("error reading manifest: %w", err)
+		}
+		var manifest Manifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return fmt.Errorf("error unmarshalling manifest: %w", err)
+		}
+		if manifest.SchemaVersion > schemaVersion {
+			return fmt.Errorf("manifest schema version %d is newer than this gokp supports (%d)", manifest.SchemaVersion, schemaVersion)
+		}
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		db, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open Keepass database: %w", err)
+		}
+
+		changed, err := importManifestDatabases(db, manifest.Databases, dryRun, promptPassword)
+		if err != nil {
+			return err
+		}
+		changed += importManifestFavorites(db, manifest.Favorites, dryRun)
+
+		if dryRun || changed == 0 {
+			fmt.Println("\nNo changes written.")
+			return nil
+		}
+		if err := saveKeepassDB(db, gokpKDBX); err != nil {
+			return fmt.Errorf("failed to save Keepass database: %w", err)
+		}
+		fmt.Printf("\nSuccessfully imported %d item(s).\n", changed)
+		return nil
+	},
+}
+
+func buildManifest(db *gokeepasslib.Database) Manifest {
+	manifest := Manifest{
+		SchemaVersion: schemaVersion,
+		Exported:      getCurrentTimestamp("iso"),
+		Databases:     []ManifestDatabase{},
+		Favorites:     []ManifestFavorite{},
+	}
+
+	if databases := FindRootGroupByName(db.Content.Root.Groups, databasesGroupName); databases != nil {
+		for _, entry := range databases.Entries {
+			manifest.Databases = append(manifest.Databases, ManifestDatabase{
+				Name:    entry.GetTitle(),
+				Path:    templatePath(entry.GetContent(attrDatabasePath)),
+				KeyFile: templatePath(entry.GetContent(attrKeyFilePath)),
+			})
+		}
+	}
+
+	if favorites := FindRootGroupByName(db.Content.Root.Groups, favoritesGroupName); favorites != nil {
+		for _, entry := range favorites.Entries {
+			index, _ := strconv.Atoi(entry.GetContent(attrFavoriteIndex))
+			manifest.Favorites = append(manifest.Favorites, ManifestFavorite{
+				Database: entry.GetContent(attrDatabaseSource),
+				UUID:     entry.GetContent(attrDatabaseUUID),
+				Index:    index,
+				Alias:    entry.GetTitle(),
+			})
+		}
+	}
+
+	return manifest
+}
+
+// importManifestDatabases registers every manifest database that isn't registered yet,
+// asking askPassword for their passwords, and returns how many were added
+func importManifestDatabases(db *gokeepasslib.Database, databases []ManifestDatabase, dryRun bool, askPassword func(prompt string) (string, error)) (int, error) {
+	added := 0
+	fmt.Println("Databases:")
+	for _, mdb := range databases {
+		path := expandPath(mdb.Path)
+		keyFile := expandPath(mdb.KeyFile)
+
+		if name := registeredDatabaseName(db, path); name != "" {
+			fmt.Printf("- %s: already registered as '%s', skipping.\n", mdb.Name, name)
+			continue
+		}
+		if readEntryFromGroup(db, databasesGroupName, mdb.Name) != nil {
+			fmt.Printf("- %s: name already registered with a different path, skipping.\n", mdb.Name)
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			fmt.Printf("- %s: file %s not found, skipping.\n", mdb.Name, path)
+			continue
+		}
+		if dryRun {
+			fmt.Printf("- %s: would register %s\n", mdb.Name, path)
+			continue
+		}
+
+		password, err := askPassword(fmt.Sprintf("Password for '%s' (%s, leave empty to skip): ", mdb.Name, path))
+		if err != nil {
+			return added, err
+		}
+		if password == "" {
+			fmt.Printf("- %s: skipped.\n", mdb.Name)
+			continue
+		}
+		if _, err := openExternalKeepassDB(path, password, keyFile); err != nil {
+			fmt.Printf("- %s: failed to open database: %v, skipping.\n", mdb.Name, err)
+			continue
+		}
+
+		addGoKPEntryToGroup(db, databasesGroupName, mdb.Name, password, path, keyFile)
+		added++
+		fmt.Printf("- %s: registered.\n", mdb.Name)
+	}
+	return added, nil
+}
+
+// importManifestFavorites re-creates favorites from their source entries, keeping the
+// manifest index and alias where possible, and returns how many were added
+func importManifestFavorites(db *gokeepasslib.Database, favorites []ManifestFavorite, dryRun bool) int {
+	added := 0
+	opened := map[string]*gokeepasslib.Database{}
+	fmt.Println("\nFavorites:")
+	for _, fav := range favorites {
+		label := fmt.Sprintf("#%d %s", fav.Index, fav.Alias)
+
+		if findFavoriteBySource(db, fav.Database, fav.UUID) != nil {
+			fmt.Printf("- %s: already a favorite, skipping.\n", label)
+			continue
+		}
+
+		externalDB, ok := opened[fav.Database]
+		if !ok {
+			var err error
+			externalDB, err = openRegisteredDatabase(db, fav.Database)
+			if err != nil && !dryRun {
+				fmt.Printf("- %s: %v, skipping.\n", label, err)
+				continue
+			}
+			opened[fav.Database] = externalDB
+		}
+		if externalDB == nil {
+			fmt.Printf("- %s: would add from '%s' once the database is registered\n", label, fav.Database)
+			continue
+		}
+
+		entry := findEntryByUUID(externalDB, fav.UUID)
+		if entry == nil {
+			fmt.Printf("- %s: entry %s no longer exists in '%s', skipping.\n", label, fav.UUID, fav.Database)
+			continue
+		}
+		if dryRun {
+			fmt.Printf("- %s: would add '%s' from '%s'\n", label, entry.GetTitle(), fav.Database)
+			continue
+		}
+
+		dbEntry := readEntryFromGroup(db, databasesGroupName, fav.Database)
+		result := SearchResult{Entry: *entry, DatabaseName: fav.Database, DatabasePath: dbEntry.GetContent(attrDatabasePath)}
+		if err := addFavoriteEntryToGoKP(db, result); err != nil {
+			fmt.Printf("- %s: %v, skipping.\n", label, err)
+			continue
+		}
+
+		// addFavoriteEntryToGoKP appends with the next free index; restore the exported one if it is free
+		favGroup := &db.Content.Root.Groups[*FindRootGroupIndexByName(db.Content.Root.Groups, favoritesGroupName)]
+		newEntry := &favGroup.Entries[len(favGroup.Entries)-1]
+		if fav.Index > 0 && readFavoritesEntry(db, fav.Index) == nil {
+			newEntry.Get(attrFavoriteIndex).Value.Content = strconv.Itoa(fav.Index)
+		}
+		if fav.Alias != "" {
+			newEntry.Get("Title").Value.Content = fav.Alias
+		}
+		added++
+		fmt.Printf("- %s: added as #%s.\n", label, newEntry.GetContent(attrFavoriteIndex))
+	}
+	return added
+}
+
+// findFavoriteBySource returns the favorite pointing at the given entry of a source database
+func findFavoriteBySource(db *gokeepasslib.Database, source, uuid string) *gokeepasslib.Entry {
+	favorites := FindRootGroupByName(db.Content.Root.Groups, favoritesGroupName)
+	if favorites == nil {
+		return nil
+	}
+	for i := range favorites.Entries {
+		entry := &favorites.Entries[i]
+		if entry.GetContent(attrDatabaseSource) == source && strings.EqualFold(entry.GetContent(attrDatabaseUUID), uuid) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// templatePath rewrites paths under the home directory as $HOME/... for portability
+func templatePath(path string) string {
+	if path == "" {
+		return ""
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(homeDir, path); err == nil && !strings.HasPrefix(rel, "..") && filepath.IsAbs(path) {
+		return filepath.ToSlash(filepath.Join("$HOME", rel))
+	}
+	return path
+}
+
+// expandPath resolves $VARIABLES and a leading ~ in a manifest path
+func expandPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		path = filepath.Join(homeDir, path[1:])
+	}
+	return filepath.FromSlash(os.ExpandEnv(path))
+}
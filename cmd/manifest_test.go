@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	entry := newTestEntry("Title", "web", "UserName", "bob", "Password", "web-password")
+	path := registerTestDatabase(t, gokpKDBX, "prod", "prod-password", newTestDatabase("prod-password", newTestGroup("Root", entry)))
+	gokpDB := openTestGoKP(t, gokpKDBX)
+	if err := addFavoriteEntryToGoKP(gokpDB, SearchResult{Entry: entry, DatabaseName: "prod", DatabasePath: path}); err != nil {
+		t.Fatal(err)
+	}
+	favorites := FindRootGroupByName(gokpDB.Content.Root.Groups, favoritesGroupName)
+	favorites.Entries[0].Get("Title").Value.Content = "my web"
+	if err := saveKeepassDB(gokpDB, gokpKDBX); err != nil {
+		t.Fatal(err)
+	}
+
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	if err := runGoKP(t, "export", "--no-secrets", "-o", manifestPath); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"prod-password", "web-password", testGoKPPassword} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("manifest contains %q:\n%s", secret, data)
+		}
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Databases) != 1 || !strings.HasPrefix(manifest.Databases[0].Path, "$HOME/") {
+		t.Errorf("databases = %+v, want prod with a $HOME path", manifest.Databases)
+	}
+	if len(manifest.Favorites) != 1 {
+		t.Fatalf("favorites = %+v, want one", manifest.Favorites)
+	}
+	favIndex := manifest.Favorites[0].Index
+
+	// Import into an empty registry
+	empty := newTestDatabase(testGoKPPassword, newTestGroup(databasesGroupName), newTestGroup(favoritesGroupName))
+	setDBSchemaVersion(empty, schemaVersion)
+	var prompts int
+	askPassword := func(string) (string, error) {
+		prompts++
+		return "prod-password", nil
+	}
+	added, err := importManifestDatabases(empty, manifest.Databases, false, askPassword)
+	if err != nil || added != 1 {
+		t.Fatalf("importManifestDatabases = %d, %v, want 1 database added", added, err)
+	}
+	if added := importManifestFavorites(empty, manifest.Favorites, false); added != 1 {
+		t.Fatalf("importManifestFavorites = %d, want 1 favorite added", added)
+	}
+	dbEntry := readEntryFromGroup(empty, databasesGroupName, "prod")
+	if dbEntry == nil || getEntryAttribute(dbEntry, attrDatabasePath) != path || dbEntry.GetPassword() != "prod-password" {
+		t.Errorf("imported database = %+v, want prod at %s", dbEntry, path)
+	}
+	if fav := readFavoritesEntry(empty, favIndex); fav == nil || fav.GetTitle() != "my web" || fav.GetPassword() != "web-password" {
+		t.Errorf("imported favorite = %+v, want #%d 'my web' from the source entry", fav, favIndex)
+	}
+
+	// Importing again changes nothing and asks for no passwords
+	if err := saveKeepassDB(empty, gokpKDBX); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(gokpKDBX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(nonInteractiveEnv, "1")
+	if err := runGoKP(t, "import", "-i", manifestPath); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.ReadFile(gokpKDBX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("importing the manifest again rewrote gokp.kdbx")
+	}
+	if prompts != 1 {
+		t.Errorf("asked for %d passwords, want 1", prompts)
+	}
+}
+
+func TestManifestLeavesKeyFileContentsOut(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	keyFile := filepath.Join(filepath.Dir(gokpKDBX), "prod.keyx")
+	if err := os.WriteFile(keyFile, []byte("key-file-contents"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	gokpDB := openTestGoKP(t, gokpKDBX)
+	addGoKPEntryToGroup(gokpDB, databasesGroupName, "prod", "prod-password", filepath.Join(filepath.Dir(gokpKDBX), "prod.kdbx"), keyFile)
+
+	manifest := buildManifest(gokpDB)
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"prod-password", "key-file-contents"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("manifest contains %q: %s", secret, data)
+		}
+	}
+	if got := manifest.Databases[0].KeyFile; got != "$HOME/.gokp/prod.keyx" {
+		t.Errorf("key file = %q, want its path under $HOME", got)
+	}
+}
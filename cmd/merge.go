@@ -0,0 +1,450 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+// mergeReport describes what mergeDatabases changed in the destination database
+type mergeReport struct {
+	Added     []string
+	Updated   []string
+	Deleted   []string
+	Moved     []string
+	Conflicts []string
+
+	// historyChanged is set when only entry histories or deleted objects changed
+	historyChanged bool
+}
+
+func (r *mergeReport) changes() int {
+	return len(r.Added) + len(r.Updated) + len(r.Deleted) + len(r.Moved)
+}
+
+// modified reports whether the destination database needs to be written
+func (r *mergeReport) modified() bool {
+	return r.changes() > 0 || r.historyChanged
+}
+
+// mergeDatabases merges src into dst the way KeePass synchronizes databases. Objects are
+// matched by UUID and the most recently modified version wins; the version that loses is
+// kept in the entry's history. Changes made on both sides since the last common version,
+// found through the entry histories, are reported as conflicts. Objects listed as deleted
+// in either database are removed unless they were modified after the deletion.
+func mergeDatabases(dst, src *gokeepasslib.Database) *mergeReport {
+	m := &merger{dst: dst, src: src, report: &mergeReport{}, deleted: map[gokeepasslib.UUID]time.Time{}}
+	m.collectDeletedObjects()
+
+	srcRoot := src.Content.Root.Groups
+	dstRoot := dst.Content.Root.Groups
+	if len(srcRoot) == 0 || len(dstRoot) == 0 {
+		return m.report
+	}
+	// Databases that didn't start as copies of each other still share a root group
+	m.rootAlias = map[gokeepasslib.UUID]gokeepasslib.UUID{}
+	if findGroup(dstRoot, srcRoot[0].UUID) == nil {
+		m.rootAlias[srcRoot[0].UUID] = dstRoot[0].UUID
+	}
+
+	m.mergeGroups(srcRoot, gokeepasslib.UUID{})
+	m.mergeEntries(srcRoot)
+	m.applyDeletions()
+	m.trimHistories()
+	return m.report
+}
+
+type merger struct {
+	dst, src  *gokeepasslib.Database
+	report    *mergeReport
+	deleted   map[gokeepasslib.UUID]time.Time
+	rootAlias map[gokeepasslib.UUID]gokeepasslib.UUID
+}
+
+// collectDeletedObjects records the deletions of both databases in dst and in m.deleted
+func (m *merger) collectDeletedObjects() {
+	known := len(m.dst.Content.Root.DeletedObjects)
+	for _, db := range []*gokeepasslib.Database{m.dst, m.src} {
+		for _, object := range db.Content.Root.DeletedObjects {
+			deletedAt := timeOf(object.DeletionTime)
+			if current, ok := m.deleted[object.UUID]; !ok || deletedAt.After(current) {
+				m.deleted[object.UUID] = deletedAt
+			}
+		}
+	}
+
+	objects := make([]gokeepasslib.DeletedObjectData, 0, len(m.deleted))
+	for uuid, deletedAt := range m.deleted {
+		deletionTime := w.TimeWrapper{Time: deletedAt}
+		objects = append(objects, gokeepasslib.DeletedObjectData{UUID: uuid, DeletionTime: &deletionTime})
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].DeletionTime.Time.Before(objects[j].DeletionTime.Time)
+	})
+	m.dst.Content.Root.DeletedObjects = objects
+	if len(objects) != known {
+		m.report.historyChanged = true
+	}
+}
+
+// isDeleted reports whether an object was deleted after it was last modified
+func (m *merger) isDeleted(uuid gokeepasslib.UUID, times gokeepasslib.TimeData) bool {
+	deletedAt, ok := m.deleted[uuid]
+	return ok && !deletedAt.Before(timeOf(times.LastModificationTime))
+}
+
+func (m *merger) alias(uuid gokeepasslib.UUID) gokeepasslib.UUID {
+	if aliased, ok := m.rootAlias[uuid]; ok {
+		return aliased
+	}
+	return uuid
+}
+
+// targetGroup returns the dst group matching a src group, or the dst root group
+func (m *merger) targetGroup(uuid gokeepasslib.UUID) *gokeepasslib.Group {
+	if group := findGroup(m.dst.Content.Root.Groups, m.alias(uuid)); group != nil {
+		return group
+	}
+	return &m.dst.Content.Root.Groups[0]
+}
+
+// mergeGroups adds new src groups to dst and applies newer group names, notes and moves
+func (m *merger) mergeGroups(groups []gokeepasslib.Group, parent gokeepasslib.UUID) {
+	for i := range groups {
+		group := &groups[i]
+		if m.isDeleted(group.UUID, group.Times) {
+			continue
+		}
+
+		existing := findGroup(m.dst.Content.Root.Groups, m.alias(group.UUID))
+		switch {
+		case existing == nil:
+			added := *group
+			added.Entries = nil
+			added.Groups = nil
+			target := m.targetGroup(parent)
+			target.Groups = append(target.Groups, added)
+			m.report.Added = append(m.report.Added, fmt.Sprintf("group '%s'", group.Name))
+		case timeOf(group.Times.LastModificationTime).After(timeOf(existing.Times.LastModificationTime)):
+			existing.Name = group.Name
+			existing.Notes = group.Notes
+			existing.IconID = group.IconID
+			existing.Times = group.Times
+			m.report.Updated = append(m.report.Updated, fmt.Sprintf("group '%s'", group.Name))
+		}
+
+		if existing != nil && parent != (gokeepasslib.UUID{}) {
+			m.moveGroup(group, parent)
+		}
+		m.mergeGroups(group.Groups, group.UUID)
+	}
+}
+
+// moveGroup moves a dst group under the group matching parent if it moved later in src
+func (m *merger) moveGroup(group *gokeepasslib.Group, parent gokeepasslib.UUID) {
+	dstParent := findParentGroup(m.dst.Content.Root.Groups, m.alias(group.UUID))
+	if dstParent == nil || dstParent.UUID.Compare(m.alias(parent)) {
+		return
+	}
+	existing := findGroup(m.dst.Content.Root.Groups, m.alias(group.UUID))
+	if !timeOf(group.Times.LocationChanged).After(timeOf(existing.Times.LocationChanged)) {
+		return
+	}
+	target := findGroup(m.dst.Content.Root.Groups, m.alias(parent))
+	if target == nil || findGroup(existing.Groups, target.UUID) != nil {
+		return
+	}
+
+	moved := *existing
+	moved.Times.LocationChanged = group.Times.LocationChanged
+	removeGroup(dstParent, existing.UUID)
+	// Removing shifted the slices, look the target up again
+	target = findGroup(m.dst.Content.Root.Groups, m.alias(parent))
+	target.Groups = append(target.Groups, moved)
+	m.report.Moved = append(m.report.Moved, fmt.Sprintf("group '%s' to '%s'", moved.Name, target.Name))
+}
+
+// mergeEntries merges every src entry into dst
+func (m *merger) mergeEntries(groups []gokeepasslib.Group) {
+	for i := range groups {
+		for j := range groups[i].Entries {
+			m.mergeEntry(&groups[i].Entries[j], groups[i].UUID)
+		}
+		m.mergeEntries(groups[i].Groups)
+	}
+}
+
+func (m *merger) mergeEntry(entry *gokeepasslib.Entry, parent gokeepasslib.UUID) {
+	if m.isDeleted(entry.UUID, entry.Times) {
+		return
+	}
+
+	existing := findEntry(m.dst.Content.Root.Groups, entry.UUID)
+	if existing == nil {
+		target := m.targetGroup(parent)
+		target.Entries = append(target.Entries, m.copyEntry(entry))
+		m.report.Added = append(m.report.Added, fmt.Sprintf("entry '%s'", entry.GetTitle()))
+		return
+	}
+
+	srcTime := timeOf(entry.Times.LastModificationTime)
+	dstTime := timeOf(existing.Times.LastModificationTime)
+	switch {
+	case srcTime.After(dstTime):
+		// A conflict is a local change the other side never saw
+		if !entryHistoryHas(entry, dstTime) {
+			m.report.Conflicts = append(m.report.Conflicts, fmt.Sprintf("entry '%s' changed in both, kept the newer version from the other database", entry.GetTitle()))
+		}
+		merged := m.copyEntry(entry)
+		merged.Histories = mergeHistories(merged.Histories, existing.Histories, []gokeepasslib.Entry{withoutHistory(*existing)})
+		*existing = merged
+		m.report.Updated = append(m.report.Updated, fmt.Sprintf("entry '%s'", entry.GetTitle()))
+	case dstTime.After(srcTime):
+		if !entryHistoryHas(existing, srcTime) {
+			m.report.Conflicts = append(m.report.Conflicts, fmt.Sprintf("entry '%s' changed in both, kept the newer local version", entry.GetTitle()))
+		}
+		if !entryHistoryHas(existing, srcTime) || entryHistoryMissing(existing, entry) {
+			theirs := m.copyEntry(entry)
+			existing.Histories = mergeHistories(existing.Histories, theirs.Histories, []gokeepasslib.Entry{withoutHistory(theirs)})
+			m.report.historyChanged = true
+		}
+	default:
+		if entryHistoryMissing(existing, entry) {
+			theirs := m.copyEntry(entry)
+			existing.Histories = mergeHistories(existing.Histories, theirs.Histories, nil)
+			m.report.historyChanged = true
+		}
+	}
+
+	m.moveEntry(entry, parent)
+}
+
+// moveEntry moves a dst entry under the group matching parent if it moved later in src
+func (m *merger) moveEntry(entry *gokeepasslib.Entry, parent gokeepasslib.UUID) {
+	dstParent := findEntryParent(m.dst.Content.Root.Groups, entry.UUID)
+	target := findGroup(m.dst.Content.Root.Groups, m.alias(parent))
+	if dstParent == nil || target == nil || dstParent.UUID.Compare(target.UUID) {
+		return
+	}
+	existing := findEntry(m.dst.Content.Root.Groups, entry.UUID)
+	if !timeOf(entry.Times.LocationChanged).After(timeOf(existing.Times.LocationChanged)) {
+		return
+	}
+
+	moved := *existing
+	moved.Times.LocationChanged = entry.Times.LocationChanged
+	removeEntry(dstParent, entry.UUID)
+	target.Entries = append(target.Entries, moved)
+	m.report.Moved = append(m.report.Moved, fmt.Sprintf("entry '%s' to '%s'", moved.GetTitle(), target.Name))
+}
+
+// applyDeletions removes dst entries and empty groups deleted after their last change
+func (m *merger) applyDeletions() {
+	for uuid := range m.deleted {
+		if entry := findEntry(m.dst.Content.Root.Groups, uuid); entry != nil && m.isDeleted(uuid, entry.Times) {
+			title := entry.GetTitle()
+			removeEntry(findEntryParent(m.dst.Content.Root.Groups, uuid), uuid)
+			m.report.Deleted = append(m.report.Deleted, fmt.Sprintf("entry '%s'", title))
+		}
+	}
+	for uuid := range m.deleted {
+		group := findGroup(m.dst.Content.Root.Groups, uuid)
+		if group == nil || !m.isDeleted(uuid, group.Times) {
+			continue
+		}
+		if len(group.Entries) > 0 || len(group.Groups) > 0 {
+			m.report.Conflicts = append(m.report.Conflicts, fmt.Sprintf("group '%s' was deleted but still has content, kept it", group.Name))
+			continue
+		}
+		if parent := findParentGroup(m.dst.Content.Root.Groups, uuid); parent != nil {
+			name := group.Name
+			removeGroup(parent, uuid)
+			m.report.Deleted = append(m.report.Deleted, fmt.Sprintf("group '%s'", name))
+		}
+	}
+}
+
+// trimHistories applies the history size limit of dst
+func (m *merger) trimHistories() {
+	limit := int64(-1)
+	if m.dst.Content.Meta != nil {
+		limit = m.dst.Content.Meta.HistoryMaxItems
+	}
+	if limit < 0 {
+		return
+	}
+	forEachEntry(m.dst.Content.Root.Groups, nil, func(_ []string, entry *gokeepasslib.Entry) {
+		for i := range entry.Histories {
+			if extra := len(entry.Histories[i].Entries) - int(limit); extra > 0 {
+				entry.Histories[i].Entries = entry.Histories[i].Entries[extra:]
+				m.report.historyChanged = true
+			}
+		}
+	})
+}
+
+// copyEntry deep copies a src entry for dst, re-adding its attachments to dst
+func (m *merger) copyEntry(entry *gokeepasslib.Entry) gokeepasslib.Entry {
+	copied := *entry
+	copied.Values = append([]gokeepasslib.ValueData{}, entry.Values...)
+	copied.CustomData = append([]gokeepasslib.CustomData{}, entry.CustomData...)
+	copied.AutoType.Associations = append([]gokeepasslib.AutoTypeAssociation{}, entry.AutoType.Associations...)
+
+	copied.Binaries = nil
+	for _, ref := range entry.Binaries {
+		content, err := readEntryAttachment(m.src, entry, ref.Name)
+		if err != nil {
+			continue
+		}
+		copied.Binaries = append(copied.Binaries, m.dst.AddBinary(content).CreateReference(ref.Name))
+	}
+
+	copied.Histories = nil
+	for _, history := range entry.Histories {
+		var versions []gokeepasslib.Entry
+		for i := range history.Entries {
+			versions = append(versions, m.copyEntry(&history.Entries[i]))
+		}
+		copied.Histories = append(copied.Histories, gokeepasslib.History{Entries: versions})
+	}
+	return copied
+}
+
+// mergeHistories combines history versions, dropping duplicates by modification time
+func mergeHistories(histories []gokeepasslib.History, others []gokeepasslib.History, extra []gokeepasslib.Entry) []gokeepasslib.History {
+	var versions []gokeepasslib.Entry
+	seen := map[int64]bool{}
+	add := func(entry gokeepasslib.Entry) {
+		key := timeOf(entry.Times.LastModificationTime).Unix()
+		if !seen[key] {
+			seen[key] = true
+			versions = append(versions, entry)
+		}
+	}
+	for _, group := range [][]gokeepasslib.History{histories, others} {
+		for _, history := range group {
+			for _, entry := range history.Entries {
+				add(entry)
+			}
+		}
+	}
+	for _, entry := range extra {
+		add(entry)
+	}
+
+	if len(versions) == 0 {
+		return nil
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return timeOf(versions[i].Times.LastModificationTime).Before(timeOf(versions[j].Times.LastModificationTime))
+	})
+	return []gokeepasslib.History{{Entries: versions}}
+}
+
+// entryHistoryHas reports whether the entry has a history version modified at t
+func entryHistoryHas(entry *gokeepasslib.Entry, t time.Time) bool {
+	for _, history := range entry.Histories {
+		for _, version := range history.Entries {
+			if timeOf(version.Times.LastModificationTime).Unix() == t.Unix() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// entryHistoryMissing reports whether other has history versions entry doesn't have
+func entryHistoryMissing(entry, other *gokeepasslib.Entry) bool {
+	for _, history := range other.Histories {
+		for _, version := range history.Entries {
+			if !entryHistoryHas(entry, timeOf(version.Times.LastModificationTime)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func withoutHistory(entry gokeepasslib.Entry) gokeepasslib.Entry {
+	entry.Histories = nil
+	return entry
+}
+
+func timeOf(t *w.TimeWrapper) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.Time
+}
+
+func findGroup(groups []gokeepasslib.Group, uuid gokeepasslib.UUID) *gokeepasslib.Group {
+	for i := range groups {
+		if groups[i].UUID.Compare(uuid) {
+			return &groups[i]
+		}
+		if group := findGroup(groups[i].Groups, uuid); group != nil {
+			return group
+		}
+	}
+	return nil
+}
+
+func findParentGroup(groups []gokeepasslib.Group, uuid gokeepasslib.UUID) *gokeepasslib.Group {
+	for i := range groups {
+		for j := range groups[i].Groups {
+			if groups[i].Groups[j].UUID.Compare(uuid) {
+				return &groups[i]
+			}
+		}
+		if parent := findParentGroup(groups[i].Groups, uuid); parent != nil {
+			return parent
+		}
+	}
+	return nil
+}
+
+func findEntry(groups []gokeepasslib.Group, uuid gokeepasslib.UUID) *gokeepasslib.Entry {
+	if parent := findEntryParent(groups, uuid); parent != nil {
+		for i := range parent.Entries {
+			if parent.Entries[i].UUID.Compare(uuid) {
+				return &parent.Entries[i]
+			}
+		}
+	}
+	return nil
+}
+
+func findEntryParent(groups []gokeepasslib.Group, uuid gokeepasslib.UUID) *gokeepasslib.Group {
+	for i := range groups {
+		for j := range groups[i].Entries {
+			if groups[i].Entries[j].UUID.Compare(uuid) {
+				return &groups[i]
+			}
+		}
+		if parent := findEntryParent(groups[i].Groups, uuid); parent != nil {
+			return parent
+		}
+	}
+	return nil
+}
+
+func removeGroup(parent *gokeepasslib.Group, uuid gokeepasslib.UUID) {
+	for i := range parent.Groups {
+		if parent.Groups[i].UUID.Compare(uuid) {
+			parent.Groups = append(parent.Groups[:i], parent.Groups[i+1:]...)
+			return
+		}
+	}
+}
+
+func removeEntry(parent *gokeepasslib.Group, uuid gokeepasslib.UUID) {
+	for i := range parent.Entries {
+		if parent.Entries[i].UUID.Compare(uuid) {
+			parent.Entries = append(parent.Entries[:i], parent.Entries[i+1:]...)
+			return
+		}
+	}
+}
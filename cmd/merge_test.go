@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+func TestMergeDatabases(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+	uuid := gokeepasslib.NewUUID()
+	version := func(password string, modified time.Time, history ...gokeepasslib.Entry) gokeepasslib.Entry {
+		entry := newTestEntry("Title", "web", "Password", password)
+		entry.UUID = uuid
+		entry.Times.LastModificationTime = &w.TimeWrapper{Time: modified}
+		entry.Times.LocationChanged = &w.TimeWrapper{Time: base}
+		if len(history) > 0 {
+			entry.Histories = []gokeepasslib.History{{Entries: history}}
+		}
+		return entry
+	}
+	deletedAt := func(deleted time.Time) []gokeepasslib.DeletedObjectData {
+		return []gokeepasslib.DeletedObjectData{{UUID: uuid, DeletionTime: &w.TimeWrapper{Time: deleted}}}
+	}
+
+	original := version("v1", at(0))
+	tests := []struct {
+		name      string
+		dst       []gokeepasslib.Entry
+		src       []gokeepasslib.Entry
+		deleted   []gokeepasslib.DeletedObjectData
+		want      mergeReport
+		password  string
+		history   []string
+		conflicts int
+	}{
+		{
+			name:     "newer version from the other side",
+			dst:      []gokeepasslib.Entry{original},
+			src:      []gokeepasslib.Entry{version("v2", at(1), original)},
+			want:     mergeReport{Updated: []string{"entry 'web'"}},
+			password: "v2",
+			history:  []string{"v1"},
+		},
+		{
+			name:      "changed on both sides, theirs newer",
+			dst:       []gokeepasslib.Entry{version("mine", at(1), original)},
+			src:       []gokeepasslib.Entry{version("theirs", at(2), original)},
+			want:      mergeReport{Updated: []string{"entry 'web'"}},
+			password:  "theirs",
+			history:   []string{"v1", "mine"},
+			conflicts: 1,
+		},
+		{
+			name:      "changed on both sides, mine newer",
+			dst:       []gokeepasslib.Entry{version("mine", at(2), original)},
+			src:       []gokeepasslib.Entry{version("theirs", at(1), original)},
+			want:      mergeReport{historyChanged: true},
+			password:  "mine",
+			history:   []string{"v1", "theirs"},
+			conflicts: 1,
+		},
+		{
+			name:     "other side is behind",
+			dst:      []gokeepasslib.Entry{version("v2", at(1), original)},
+			src:      []gokeepasslib.Entry{original},
+			password: "v2",
+			history:  []string{"v1"},
+		},
+		{
+			name:     "same version with a longer history",
+			dst:      []gokeepasslib.Entry{version("v2", at(1))},
+			src:      []gokeepasslib.Entry{version("v2", at(1), original)},
+			want:     mergeReport{historyChanged: true},
+			password: "v2",
+			history:  []string{"v1"},
+		},
+		{
+			name:     "new entry",
+			src:      []gokeepasslib.Entry{original},
+			want:     mergeReport{Added: []string{"entry 'web'"}},
+			password: "v1",
+		},
+		{
+			name:    "deleted after the last change",
+			dst:     []gokeepasslib.Entry{original},
+			deleted: deletedAt(at(1)),
+			want:    mergeReport{Deleted: []string{"entry 'web'"}, historyChanged: true},
+		},
+		{
+			name:     "changed after the deletion",
+			dst:      []gokeepasslib.Entry{version("v2", at(2), original)},
+			deleted:  deletedAt(at(1)),
+			want:     mergeReport{historyChanged: true},
+			password: "v2",
+			history:  []string{"v1"},
+		},
+	}
+	for _, tt := range tests {
+		dstRoot := newTestGroup("Root", tt.dst...)
+		srcRoot := dstRoot
+		srcRoot.Entries = tt.src
+		dst := newTestDatabase("password", dstRoot)
+		src := newTestDatabase("password", srcRoot)
+		src.Content.Root.DeletedObjects = tt.deleted
+
+		report := mergeDatabases(dst, src)
+		if len(report.Conflicts) != tt.conflicts {
+			t.Errorf("%s: conflicts = %q, want %d", tt.name, report.Conflicts, tt.conflicts)
+		}
+		report.Conflicts = nil
+		if !reflect.DeepEqual(*report, tt.want) {
+			t.Errorf("%s: report = %+v, want %+v", tt.name, *report, tt.want)
+		}
+
+		entry := findEntry(dst.Content.Root.Groups, uuid)
+		if tt.password == "" {
+			if entry != nil {
+				t.Errorf("%s: entry was kept", tt.name)
+			}
+			continue
+		}
+		if entry == nil {
+			t.Errorf("%s: entry is gone", tt.name)
+			continue
+		}
+		if got := entry.GetPassword(); got != tt.password {
+			t.Errorf("%s: password = %q, want %q", tt.name, got, tt.password)
+		}
+		if history := historyPasswords(entry); !reflect.DeepEqual(history, tt.history) {
+			t.Errorf("%s: history = %q, want %q", tt.name, history, tt.history)
+		}
+	}
+}
+
+func TestMergeDatabasesTrimsHistory(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry := newTestEntry("Title", "web", "Password", "v4")
+	entry.Times.LastModificationTime = &w.TimeWrapper{Time: base.Add(4 * time.Hour)}
+	entry.Histories = []gokeepasslib.History{{}}
+	for i, password := range []string{"v1", "v2", "v3"} {
+		old := newTestEntry("Title", "web", "Password", password)
+		old.UUID = entry.UUID
+		old.Times.LastModificationTime = &w.TimeWrapper{Time: base.Add(time.Duration(i) * time.Hour)}
+		entry.Histories[0].Entries = append(entry.Histories[0].Entries, old)
+	}
+	dst := newTestDatabase("password", newTestGroup("Root"))
+	dst.Content.Meta.HistoryMaxItems = 2
+	src := newTestDatabase("password", newTestGroup("Root", entry))
+
+	mergeDatabases(dst, src)
+	merged := findEntry(dst.Content.Root.Groups, entry.UUID)
+	if merged == nil {
+		t.Fatal("entry was not added")
+	}
+	if history, want := historyPasswords(merged), []string{"v2", "v3"}; !reflect.DeepEqual(history, want) {
+		t.Errorf("history = %q, want %q", history, want)
+	}
+}
+
+// historyPasswords returns the passwords of an entry's history versions, oldest first
+func historyPasswords(entry *gokeepasslib.Entry) []string {
+	var passwords []string
+	for _, history := range entry.Histories {
+		for _, version := range history.Entries {
+			passwords = append(passwords, version.GetPassword())
+		}
+	}
+	return passwords
+}
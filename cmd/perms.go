@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Permissions used for everything gokp creates under ~/.gokp
+const (
+	gokpDirMode  os.FileMode = 0700
+	gokpFileMode os.FileMode = 0600
+)
+
+// permissionsApply reports whether unix permission bits are meaningful on this OS
+func permissionsApply() bool {
+	return runtime.GOOS != "windows"
+}
+
+// isGroupOrWorldAccessible reports whether path can be accessed by anyone other than its owner
+func isGroupOrWorldAccessible(path string) (bool, os.FileMode, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, 0, err
+	}
+	mode := info.Mode().Perm()
+	return permissionsApply() && mode&0077 != 0, mode, nil
+}
+
+// checkSecretFilePermissions warns loudly when a secret file (gokp.kdbx or a key file) is
+// readable by group or others. With strict-permissions set in the config.json of gokpFolder
+// it refuses instead.
+func checkSecretFilePermissions(path, gokpFolder string) error {
+	insecure, mode, err := isGroupOrWorldAccessible(path)
+	if err != nil || !insecure {
+		return nil
+	}
+
+	strict := false
+	if config, err := readConfigFileIn(gokpFolder); err == nil {
+		strict = config.StrictPermissions
+	}
+
+	if strict {
+		return fmt.Errorf("refusing to use %s: it is accessible by group or others (%04o), run `chmod 600 %s` or `gokp doctor --fix-perms`", path, mode, path)
+	}
+	fmt.Fprintf(os.Stderr, "%sWARNING: %s is accessible by group or others (%04o). Run `chmod 600 %s` or `gokp doctor --fix-perms`.%s\n", ColorBoldRed, path, mode, path, ColorReset)
+	return nil
+}
+
+// writeSecretFile writes data to path with owner-only permissions. An existing file is
+// replaced rather than written to, so the data never sits in a file others can read.
+func writeSecretFile(path string, data []byte) error {
+	return replaceFile(path, gokpFileMode, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// replaceFile has write fill a temporary file created with mode in the folder of path, which
+// then replaces path, so a failed write or a crash never leaves a partially written file
+func replaceFile(path string, mode os.FileMode, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for '%s': %w", path, err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if err := tmp.Chmod(mode); err != nil && permissionsApply() {
+		tmp.Close()
+		return err
+	}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// fixGoKPPermissions resets the .gokp folder to 0700 and every file directly inside it to 0600,
+// returning a description of each change made
+func fixGoKPPermissions(gokpFolder string) ([]string, error) {
+	var changed []string
+
+	info, err := os.Stat(gokpFolder)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm() != gokpDirMode {
+		if err := os.Chmod(gokpFolder, gokpDirMode); err != nil {
+			return changed, err
+		}
+		changed = append(changed, fmt.Sprintf("%s: %04o -> %04o", gokpFolder, info.Mode().Perm(), gokpDirMode))
+	}
+
+	entries, err := os.ReadDir(gokpFolder)
+	if err != nil {
+		return changed, err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(gokpFolder, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			return changed, err
+		}
+		if info.Mode().Perm()&0077 == 0 {
+			continue
+		}
+		if err := os.Chmod(path, gokpFileMode); err != nil {
+			return changed, err
+		}
+		changed = append(changed, fmt.Sprintf("%s: %04o -> %04o", path, info.Mode().Perm(), gokpFileMode))
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSaveKeepassDBReplacesFileWithOwnerOnlyMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gokp.kdbx")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db := newTestDatabase("pw", newTestGroup(databasesGroupName))
+	if err := saveKeepassDB(db, path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != gokpFileMode {
+		t.Errorf("mode = %04o, want %04o", mode, gokpFileMode)
+	}
+	if _, err := decodeKeepassDB(path, "pw"); err != nil {
+		t.Errorf("saved database doesn't open: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestCheckSecretFilePermissionsUsesConfigOfFolder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gokp.kdbx")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkSecretFilePermissions(path, dir); err != nil {
+		t.Errorf("without strict-permissions: %v, want a warning only", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"strict-permissions": true}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSecretFilePermissions(path, dir); err == nil {
+		t.Error("with strict-permissions: no error, want a refusal")
+	}
+}
+
+func TestWriteSecretFileReplacesReadableFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.env")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Anyone who opened the world-readable file keeps reading it after the write
+	old, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+
+	if err := writeSecretFile(path, []byte("s3cret")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(old); string(data) != "old" {
+		t.Errorf("secret was written into the world-readable file: %q", data)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != gokpFileMode {
+		t.Errorf("mode = %04o, want %04o", mode, gokpFileMode)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "s3cret" {
+		t.Errorf("content = %q, want %q", data, "s3cret")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
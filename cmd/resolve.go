@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// secretResolver resolves secret references against the external databases registered
+// in the GoKP database, opening each database at most once.
+//
+// References take one of two forms:
+//
+//	DATABASE/[GROUP/...]TITLE[#FIELD]   an entry in a registered database
+//	fav:INDEX[#FIELD]                   a favorite
+//
+// An entry with exactly that TITLE wins, otherwise titles containing it ignoring case
+// match, and a reference matching several entries fails. FIELD defaults to Password and
+// is matched case-insensitively.
+type secretResolver struct {
+	gokpDB *gokeepasslib.Database
+	opened map[string]*gokeepasslib.Database
+}
+
+func newSecretResolver(gokpDB *gokeepasslib.Database) *secretResolver {
+	return &secretResolver{gokpDB: gokpDB, opened: map[string]*gokeepasslib.Database{}}
+}
+
+// resolve returns the value of the field the reference points at
+func (r *secretResolver) resolve(ref string) (string, error) {
+	target, field, _ := strings.Cut(ref, "#")
+	if field == "" {
+		field = "Password"
+	}
+
+	if indexStr, ok := strings.CutPrefix(target, "fav:"); ok {
+		index, err := strconv.Atoi(indexStr)
+		if err != nil {
+			return "", newError(ErrUsage, "invalid favorite index '%s' in reference '%s'", indexStr, ref)
+		}
+		return r.favoriteField(index, field)
+	}
+
+	db, entry, err := r.resolveEntry(target)
+	if err != nil {
+		return "", err
+	}
+	return expandedEntryField(db, entry, field, ref)
+}
+
+// resolveEntry returns the entry a reference without #FIELD points at, together with the
+// database holding it. Favorites are followed to their source entry.
+func (r *secretResolver) resolveEntry(target string) (*gokeepasslib.Database, *gokeepasslib.Entry, error) {
+	if indexStr, ok := strings.CutPrefix(target, "fav:"); ok {
+		index, err := strconv.Atoi(indexStr)
+		if err != nil {
+			return nil, nil, newError(ErrUsage, "invalid favorite index '%s'", indexStr)
+		}
+		favorite := readFavoritesEntry(r.gokpDB, index)
+		if favorite == nil {
+			return nil, nil, newError(ErrNotFound, "no favorite found at index %d", index)
+		}
+		source := favorite.GetContent(attrDatabaseSource)
+		db, err := r.database(source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open database '%s' of favorite %d: %w", source, index, err)
+		}
+		entry := findEntryByUUID(db, favorite.GetContent(attrDatabaseUUID))
+		if entry == nil {
+			return nil, nil, newError(ErrNotFound, "favorite %d no longer exists in '%s'", index, source)
+		}
+		return db, entry, nil
+	}
+
+	dbName, entryPath, ok := strings.Cut(target, "/")
+	if !ok || dbName == "" || entryPath == "" {
+		return nil, nil, newError(ErrUsage, "invalid reference '%s', expected DATABASE/ENTRY or fav:INDEX", target)
+	}
+	return r.entry(dbName, entryPath)
+}
+
+// database opens a registered database by name, reusing it on later calls
+func (r *secretResolver) database(name string) (*gokeepasslib.Database, error) {
+	if db, ok := r.opened[name]; ok {
+		return db, nil
+	}
+	db, err := openRegisteredDatabase(r.gokpDB, name)
+	if err != nil {
+		return nil, err
+	}
+	r.opened[name] = db
+	return db, nil
+}
+
+// databaseName returns the registry name of a database opened by this resolver
+func (r *secretResolver) databaseName(db *gokeepasslib.Database) string {
+	for name, opened := range r.opened {
+		if opened == db {
+			return name
+		}
+	}
+	return ""
+}
+
+// databasePath returns the file path of a database opened by this resolver
+func (r *secretResolver) databasePath(db *gokeepasslib.Database) (string, error) {
+	name := r.databaseName(db)
+	dbEntry := readEntryFromGroup(r.gokpDB, databasesGroupName, name)
+	if name == "" || dbEntry == nil {
+		return "", newError(ErrNotFound, "database is not registered")
+	}
+	return getEntryAttribute(dbEntry, attrDatabasePath), nil
+}
+
+// entry finds a single entry by title, optionally prefixed with the groups leading to it.
+// An exact, case-sensitive match wins; otherwise titles containing title and group names
+// are matched case-insensitively, preferring a title that only differs in case. Other
+// fields are never searched, so a secret can't come from an entry that merely mentions it.
+func (r *secretResolver) entry(dbName, entryPath string) (*gokeepasslib.Database, *gokeepasslib.Entry, error) {
+	db, err := r.database(dbName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	segments := strings.Split(entryPath, "/")
+	title := segments[len(segments)-1]
+	groups := segments[:len(segments)-1]
+
+	type match struct {
+		groupPath []string
+		entry     *gokeepasslib.Entry
+	}
+	var exact, folded, partial []match
+	forEachEntry(db.Content.Root.Groups, nil, func(groupPath []string, entry *gokeepasslib.Entry) {
+		if entry.GetTitle() == title && hasPathSuffix(groupPath, groups) {
+			exact = append(exact, match{groupPath, entry})
+		}
+		if !hasPathSuffix(lowerAll(groupPath), lowerAll(groups)) || !strings.Contains(strings.ToLower(entry.GetTitle()), strings.ToLower(title)) {
+			return
+		}
+		partial = append(partial, match{groupPath, entry})
+		if strings.EqualFold(entry.GetTitle(), title) {
+			folded = append(folded, match{groupPath, entry})
+		}
+	})
+
+	matches := partial
+	if len(exact) > 0 {
+		matches = exact
+	} else if len(folded) == 1 {
+		matches = folded
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil, newError(ErrNotFound, "no entry '%s' found in database '%s'", entryPath, dbName)
+	case 1:
+		return db, matches[0].entry, nil
+	default:
+		var refs []string
+		for _, m := range matches {
+			refs = append(refs, "  "+strings.Join(append(append([]string{dbName}, m.groupPath...), m.entry.GetTitle()), "/"))
+		}
+		return nil, nil, newError(ErrAmbiguous, "%d entries match '%s' in database '%s', use one of:\n%s", len(matches), entryPath, dbName, strings.Join(refs, "\n"))
+	}
+}
+
+// favoriteField reads a field of a favorite from its source entry. It fails rather than
+// return the copy stored in the GoKP database, which may be out of date, when the source
+// database can't be opened or the entry is gone.
+func (r *secretResolver) favoriteField(index int, field string) (string, error) {
+	db, entry, err := r.resolveEntry(fmt.Sprintf("fav:%d", index))
+	if err != nil {
+		return "", err
+	}
+	return expandedEntryField(db, entry, field, fmt.Sprintf("fav:%d#%s", index, field))
+}
+
+// entryField returns the value of field in entry, matching the key case-insensitively
+func entryField(entry *gokeepasslib.Entry, field string, ref string) (string, error) {
+	if value := entry.Get(field); value != nil {
+		return value.Value.Content, nil
+	}
+	for _, value := range entry.Values {
+		if strings.EqualFold(value.Key, field) {
+			return value.Value.Content, nil
+		}
+	}
+	return "", newError(ErrNotFound, "entry '%s' has no field '%s' (reference '%s')", entry.GetTitle(), field, ref)
+}
+
+// expandedEntryField returns the value of field in entry with field references and
+// placeholders expanded against the entry's database
+func expandedEntryField(db *gokeepasslib.Database, entry *gokeepasslib.Entry, field string, ref string) (string, error) {
+	if _, err := entryField(entry, field, ref); err != nil {
+		return "", err
+	}
+	return newFieldExpander(db).field(entry, field)
+}
+
+// lowerAll returns the lowercase versions of values
+func lowerAll(values []string) []string {
+	lower := make([]string, len(values))
+	for i, value := range values {
+		lower[i] = strings.ToLower(value)
+	}
+	return lower
+}
+
+// hasPathSuffix reports whether path ends with suffix
+func hasPathSuffix(path, suffix []string) bool {
+	if len(suffix) > len(path) {
+		return false
+	}
+	offset := len(path) - len(suffix)
+	for i := range suffix {
+		if path[offset+i] != suffix[i] {
+			return false
+		}
+	}
+	return true
+}
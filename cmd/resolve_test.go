@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestSecretResolverEntry(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	db := newTestDatabase("prod-password",
+		newTestGroup("Databases",
+			newTestEntry("Title", "postgres", "Password", "pg-password"),
+			newTestEntry("Title", "Postgres", "Password", "pg-upper-password"),
+			newTestEntry("Title", "Redis cache", "Password", "redis-password"),
+			newTestEntry("Title", "Backup", "Notes", "copy of analytics", "Password", "backup-password"),
+		),
+		newTestGroup("Web",
+			newTestEntry("Title", "postgres", "Password", "web-pg-password"),
+			newTestEntry("Title", "GitHub", "Password", "github-password"),
+		),
+	)
+	registerTestDatabase(t, gokpKDBX, "prod", "prod-password", db)
+
+	tests := []struct {
+		ref  string
+		want string
+		err  error
+	}{
+		{"prod/Databases/postgres", "pg-password", nil},
+		{"prod/Databases/Postgres", "pg-upper-password", nil},
+		{"prod/postgres", "", ErrAmbiguous},
+		{"prod/github", "github-password", nil},
+		{"prod/web/github", "github-password", nil},
+		{"prod/redis", "redis-password", nil},
+		{"prod/databases/POSTGRES", "", ErrAmbiguous},
+		{"prod/nothing-like-this", "", ErrNotFound},
+		{"prod/rdscache", "", ErrNotFound},
+		{"prod/analytics", "", ErrNotFound},
+		{"prod/Web/redis", "", ErrNotFound},
+	}
+	resolver := newSecretResolver(openTestGoKP(t, gokpKDBX))
+	for _, tt := range tests {
+		got, err := resolver.resolve(tt.ref)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("resolve(%q) error = %v, want %v", tt.ref, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("resolve(%q) error = %v", tt.ref, err)
+		} else if got != tt.want {
+			t.Errorf("resolve(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestSecretResolverFavoriteNeedsSource(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	entry := newTestEntry("Title", "web", "Password", "cached-password")
+	path := registerTestDatabase(t, gokpKDBX, "prod", "prod-password", newTestDatabase("prod-password", newTestGroup("Root", entry)))
+	gokpDB := openTestGoKP(t, gokpKDBX)
+	if err := addFavoriteEntryToGoKP(gokpDB, SearchResult{Entry: entry, DatabaseName: "prod"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The source entry is gone
+	if err := saveExternalKeepassDB(newTestDatabase("prod-password", newTestGroup("Root")), path); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := newSecretResolver(gokpDB).resolve("fav:1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("resolve with the source entry gone = %q, %v, want ErrNotFound", got, err)
+	}
+
+	// The source database can't be opened
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := newSecretResolver(gokpDB).resolve("fav:1"); err == nil {
+		t.Errorf("resolve with the source database missing = %q, want an error", got)
+	}
+}
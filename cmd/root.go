@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -10,14 +10,46 @@ import (
 var rootCmd = &cobra.Command{
 	Use:   "root-help",
 	Short: "Manage multiple keepasses used on a daily basis",
+	Long: `Manage multiple keepasses used on a daily basis.
+
+Exit codes:
+  0   success
+  1   any other error
+  2   invalid usage: unknown command or flag, wrong arguments
+  3   input is needed, but prompts are disabled (--no-input)
+  4   wrong password or key file, or an invalid key file
+  5   database, entry or favorite not found
+  6   more than one entry matches
+  7   the GoKP database is locked: no password without prompting
+  8   no external databases registered or accessible
+  9   already exists
+  10  a database changed while it was being updated
+  11  unsupported database format or KDBX version
+  12  truncated or corrupted database file
+
+Errors are printed to stderr, as JSON objects like
+{"error": "...", "code": "not_found", "exit_code": 5} with --json-errors or
+GOKP_ERROR_FORMAT=json.`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
 	// Run: func(cmd *cobra.Command, args []string) {
 	// 	println("To get started, use the help command")
 	// },
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return markError(ErrUsage, err)
+	})
+	markUsageErrors(rootCmd)
+	cmd, err := rootCmd.ExecuteC()
+	if err != nil {
+		// Cobra reports unknown commands and missing required flags without the flag error func
+		for _, prefix := range []string{"unknown command", "required flag", "if any flags in the group"} {
+			if strings.HasPrefix(err.Error(), prefix) {
+				err = markError(ErrUsage, err)
+			}
+		}
+		os.Exit(reportError(cmd, err))
 	}
 }
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().StringArrayP("env", "e", nil, "Set NAME=REFERENCE in the child environment (repeatable)")
+	// Everything from COMMAND on belongs to the child, even without "--"
+	runCmd.Flags().SetInterspersed(false)
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run --env NAME=REFERENCE [--env ...] -- COMMAND [ARGS...]",
+	Short: "Run a command with secrets injected into its environment",
+	Long: `Run a command with secrets injected into its environment.
+
+Each --env resolves a reference through the registered external databases or the
+favorites and sets it only in the environment of the child process. Values are
+never written to disk or printed. Signals are forwarded to the child and gokp
+exits with the child's exit code.
+
+References:
+  DATABASE/[GROUP/...]TITLE[#FIELD]   Entry in a registered database (FIELD defaults to Password)
+  fav:INDEX[#FIELD]                   Favorite by index
+
+An entry with exactly that TITLE wins; otherwise TITLE matches titles containing it,
+ignoring case. A reference matching several entries fails with the candidates listed.
+
+Examples:
+  gokp run --env DB_PASS=prod/postgres#Password --env API_KEY=fav:3 -- ./deploy.sh
+  gokp run -e PGUSER=prod/Databases/postgres#UserName -e PGPASSWORD=prod/Databases/postgres -- psql`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envRefs, _ := cmd.Flags().GetStringArray("env")
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		env := os.Environ()
+		resolver := newSecretResolver(gokpDB)
+		for _, envRef := range envRefs {
+			name, ref, ok := strings.Cut(envRef, "=")
+			if !ok || name == "" || ref == "" {
+				return newError(ErrUsage, "invalid --env '%s', expected NAME=REFERENCE", envRef)
+			}
+			value, err := resolver.resolve(ref)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %w", name, err)
+			}
+			env = append(env, name+"="+value)
+		}
+		closeKeepassDB(gokpDB)
+
+		os.Exit(runWithEnv(args, env))
+		return nil
+	},
+}
+
+// runWithEnv runs the command with the given environment, forwarding signals to it,
+// and returns the exit code gokp should exit with
+func runWithEnv(args []string, env []string) int {
+	child := exec.Command(args[0], args[1:]...)
+	child.Env = env
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	// Catch signals before starting so none are lost between start and forwarding
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(sigChan)
+
+	if err := child.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start %s: %v\n", args[0], err)
+		return 127
+	}
+
+	go func() {
+		for sig := range sigChan {
+			child.Process.Signal(sig)
+		}
+	}()
+
+	err := child.Wait()
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	}
+	fmt.Fprintf(os.Stderr, "Failed to run %s: %v\n", args[0], err)
+	return 1
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunPassesFlagsAfterCommandToChild(t *testing.T) {
+	defer resetFlags(runCmd)
+	if err := runCmd.ParseFlags([]string{"-e", "DB_PASS=prod/db", "psql", "-e", "select 1", "--test"}); err != nil {
+		t.Fatal(err)
+	}
+	if env, _ := runCmd.Flags().GetStringArray("env"); !reflect.DeepEqual(env, []string{"DB_PASS=prod/db"}) {
+		t.Errorf("--env = %q, want only the flag before the command", env)
+	}
+	if args, want := runCmd.Flags().Args(), []string{"psql", "-e", "select 1", "--test"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("child args = %q, want %q", args, want)
+	}
+}
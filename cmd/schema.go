@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// Layout of the gokp app database. These names are part of the on-disk schema,
+// so any change to them needs a migration below.
+const (
+	databasesGroupName = "databases"
+	favoritesGroupName = "favorites"
+
+	attrDatabasePath   = "Database Path"
+	attrKeyFilePath    = "Key File Path"
+	attrDatabaseSource = "Database Source"
+	attrDatabaseUUID   = "Database UUID"
+	attrFavoriteIndex  = "Favorite Index"
+	attrGroupPath      = "Group Path"
+)
+
+// schemaVersion is the current layout version of both config.json and gokp.kdbx
+const schemaVersion = 1
+
+// schemaVersionKey is the gokp.kdbx metadata custom data key holding the schema version
+const schemaVersionKey = "gokp.schema-version"
+
+// searchIndexKeyPrefix prefixes the gokp.kdbx custom data keys holding each database's search index
+const searchIndexKeyPrefix = "gokp.index."
+
+// migration upgrades the config and/or app database to the given version.
+// Each function reports whether it changed anything.
+type migration struct {
+	version     int
+	description string
+	config      func(config *Config) bool
+	db          func(db *gokeepasslib.Database) bool
+}
+
+var migrations = []migration{
+	{
+		version:     1,
+		description: "add missing groups and rename favorites 'Database path' to 'Database Path'",
+		config: func(config *Config) bool {
+			if config.ClipboardTimeout <= 0 {
+				config.ClipboardTimeout = 30
+				return true
+			}
+			return false
+		},
+		db: func(db *gokeepasslib.Database) bool {
+			changed := false
+			for _, name := range []string{databasesGroupName, favoritesGroupName} {
+				if FindRootGroupByName(db.Content.Root.Groups, name) == nil {
+					group := gokeepasslib.NewGroup()
+					group.Name = name
+					db.Content.Root.Groups = append(db.Content.Root.Groups, group)
+					changed = true
+				}
+			}
+
+			favGroupIndex := FindRootGroupIndexByName(db.Content.Root.Groups, favoritesGroupName)
+			entries := db.Content.Root.Groups[*favGroupIndex].Entries
+			for i := range entries {
+				for j := range entries[i].Values {
+					if entries[i].Values[j].Key == "Database path" {
+						entries[i].Values[j].Key = attrDatabasePath
+						changed = true
+					}
+				}
+			}
+			return changed
+		},
+	},
+}
+
+// getDBSchemaVersion reads the schema version from the gokp.kdbx metadata.
+// Databases created before versioning was introduced report version 0.
+func getDBSchemaVersion(db *gokeepasslib.Database) int {
+	if db.Content == nil || db.Content.Meta == nil {
+		return 0
+	}
+	for _, item := range db.Content.Meta.CustomData {
+		if item.Key == schemaVersionKey {
+			version, err := strconv.Atoi(item.Value)
+			if err != nil {
+				return 0
+			}
+			return version
+		}
+	}
+	return 0
+}
+
+func setDBSchemaVersion(db *gokeepasslib.Database, version int) {
+	if db.Content.Meta == nil {
+		db.Content.Meta = gokeepasslib.NewMetaData()
+	}
+	for i := range db.Content.Meta.CustomData {
+		if db.Content.Meta.CustomData[i].Key == schemaVersionKey {
+			db.Content.Meta.CustomData[i].Value = strconv.Itoa(version)
+			return
+		}
+	}
+	db.Content.Meta.CustomData = append(db.Content.Meta.CustomData, gokeepasslib.CustomData{
+		Key:   schemaVersionKey,
+		Value: strconv.Itoa(version),
+	})
+}
+
+// migrateGoKPDB upgrades the app database in memory to the current schema version
+// and returns a description of every migration applied
+func migrateGoKPDB(db *gokeepasslib.Database) ([]string, error) {
+	current := getDBSchemaVersion(db)
+	if current > schemaVersion {
+		return nil, fmt.Errorf("gokp database schema version %d is newer than this gokp supports (%d)", current, schemaVersion)
+	}
+
+	var applied []string
+	for _, m := range migrations {
+		if m.version <= current || m.db == nil {
+			continue
+		}
+		if m.db(db) {
+			applied = append(applied, fmt.Sprintf("v%d: %s", m.version, m.description))
+		}
+	}
+
+	if current < schemaVersion {
+		setDBSchemaVersion(db, schemaVersion)
+		if len(applied) == 0 {
+			applied = append(applied, fmt.Sprintf("v%d: record schema version", schemaVersion))
+		}
+	}
+	return applied, nil
+}
+
+// migrateConfig upgrades the config in memory to the current schema version
+// and returns a description of every migration applied
+func migrateConfig(config *Config) ([]string, error) {
+	if config.SchemaVersion > schemaVersion {
+		return nil, fmt.Errorf("config schema version %d is newer than this gokp supports (%d)", config.SchemaVersion, schemaVersion)
+	}
+
+	var applied []string
+	for _, m := range migrations {
+		if m.version <= config.SchemaVersion || m.config == nil {
+			continue
+		}
+		if m.config(config) {
+			applied = append(applied, fmt.Sprintf("v%d: %s", m.version, m.description))
+		}
+	}
+
+	if config.SchemaVersion < schemaVersion {
+		config.SchemaVersion = schemaVersion
+		if len(applied) == 0 {
+			applied = append(applied, fmt.Sprintf("v%d: record schema version", schemaVersion))
+		}
+	}
+	return applied, nil
+}
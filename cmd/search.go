@@ -2,9 +2,9 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/tobischo/gokeepasslib/v3"
@@ -18,7 +18,12 @@ func init() {
 	searchCmd.Flags().BoolP("exact", "e", false, "Exact match only (no fuzzy search)")
 	searchCmd.Flags().StringP("group", "g", "", "Search only in specific group")
 	searchCmd.Flags().StringP("database", "d", "", "Search only in specific external database")
+	searchCmd.RegisterFlagCompletionFunc("database", completeDatabaseNames)
+	searchCmd.RegisterFlagCompletionFunc("group", completeGroupNames)
 	searchCmd.Flags().BoolP("favorites", "f", false, "Select entries for favorites")
+	searchCmd.Flags().Bool("no-index", false, "Decrypt every database and also search protected field values")
+	searchCmd.Flags().StringArray("tag", nil, "Only show entries with this tag (repeatable, entries must have all tags)")
+	searchCmd.Flags().Bool("any-tag", false, "With several --tag flags, show entries that have any of the tags")
 }
 
 var searchCmd = &cobra.Command{
@@ -26,18 +31,35 @@ var searchCmd = &cobra.Command{
 	Short: "Search for and pin entries in the external Keepass databases",
 	Long: `Search for and pin entries in the external Keepass databases referenced in the GoKP database.
 
-The search will load all external databases referenced in the GoKP "databases" group
-and search their entries for matching titles, usernames, URLs, notes, and custom fields.
+The search uses an encrypted index of the titles, usernames, URLs, notes, custom fields and
+group paths of the external databases referenced in the GoKP "databases" group, stored
+in the GoKP database. Indexes are rebuilt and saved when a database file changes, or with
+'gokp manage update'. Only the selected database is decrypted when adding a favorite.
+Protected custom fields are indexed and shown by name only; with --no-index, all databases
+are decrypted and their values are searched as well.
 By default, performs case-insensitive fuzzy search across all groups in all databases.
+--tag limits the results to entries carrying every given tag, or any of them with --any-tag.
+The query may be left out when searching by tag.
 
 Examples:
   gokp search gmail                    # Fuzzy search for "gmail" across all external DBs
   gokp search -e "My Email"            # Exact match only
   gokp search -c Gmail                 # Case-sensitive search
-  gokp search -g Personal mypassword   # Search only in "Personal" group`,
-	Args: cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		query := args[0]
+  gokp search -g Personal mypassword   # Search only in "Personal" group
+  gokp search --no-index "recovery"    # Also search protected field values
+  gokp search --tag prod --tag shared  # Entries tagged both prod and shared
+  gokp search --tag prod --tag staging --any-tag db`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tags, _ := cmd.Flags().GetStringArray("tag")
+		anyTag, _ := cmd.Flags().GetBool("any-tag")
+		if len(args) == 0 && len(tags) == 0 {
+			return newError(ErrUsage, "a search query or --tag is required")
+		}
+		query := ""
+		if len(args) == 1 {
+			query = args[0]
+		}
 
 		// Get search options
 		caseSensitive, _ := cmd.Flags().GetBool("case-sensitive")
@@ -45,122 +67,120 @@ Examples:
 		targetGroup, _ := cmd.Flags().GetString("group")
 		targetDatabase, _ := cmd.Flags().GetString("database")
 		setFavorites, _ := cmd.Flags().GetBool("favorites")
+		noIndex, _ := cmd.Flags().GetBool("no-index")
 
 		_, _, gokpKDBX := pathSelection(false)
 
-		secret, err := getGoKPPassword()
+		secret, err := getGoKPPassword(gokpKDBX)
 		if err != nil {
-			log.Fatalf("Failed to get GoKP password: %v", err)
+			return fmt.Errorf("failed to get GoKP password: %w", err)
 		}
 
 		gokpDB, err := openKeepassDB(gokpKDBX, secret)
 		if err != nil {
-			log.Fatalf("Failed to open GoKP database: %v", err)
+			return fmt.Errorf("failed to open GoKP database: %w", err)
 		}
 
 		// Get all external database entries from the "databases" group
-		databasesGroup := FindRootGroupByName(gokpDB.Content.Root.Groups, "databases")
+		databasesGroup := FindRootGroupByName(gokpDB.Content.Root.Groups, databasesGroupName)
 		if databasesGroup == nil {
-			fmt.Println("No databases group found in GoKP database.")
-			return
+			return newError(ErrNotFound, "no databases group found in GoKP database")
 		}
 
 		if len(databasesGroup.Entries) == 0 {
-			fmt.Println("No external databases configured. Use 'gokp manage add' to add databases first.")
-			return
+			return newError(ErrNoDatabases, "no external databases configured. Use 'gokp manage add' to add databases first")
 		}
 
 		var allResults []SearchResult
 		totalDBsSearched := 0
+		opened := map[string]*gokeepasslib.Database{}
+
+		if noIndex {
+			for _, external := range openExternalDatabases(gokpDB, targetDatabase, os.Stdout) {
+				dbName := external.Name
+				dbPath := external.Path
+				externalDB := external.DB
+
+				totalDBsSearched++
+				opened[dbName] = externalDB
+
+				var results []gokeepasslib.Entry
+				if targetGroup != "" {
+					group := FindRootGroupByName(externalDB.Content.Root.Groups, targetGroup)
+					if group != nil {
+						results = searchEntriesInGroup(group, query, caseSensitive, exactMatch)
+					}
+				} else {
+					results = fuzzySearchEntries(externalDB, query, caseSensitive, exactMatch)
+				}
 
-		for _, dbEntry := range databasesGroup.Entries {
-			dbName := dbEntry.GetTitle()
-
-			// If filter for specific database is set, skip all that don't match
-			if targetDatabase != "" && dbName != targetDatabase {
-				continue
-			}
-
-			dbPath := getEntryAttribute(&dbEntry, "Database Path")
-			dbPassword := dbEntry.GetPassword()
-			keyFilePath := getEntryAttribute(&dbEntry, "Key File Path")
-
-			if dbPath == "" {
-				fmt.Printf("Warning: Database '%s' has no path configured, skipping.\n", dbName)
-				continue
-			}
-
-			if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-				fmt.Printf("Warning: Database file '%s' not found for database '%s', skipping.\n", dbPath, dbName)
-				continue
-			}
-
-			externalDB, err := openExternalKeepassDB(dbPath, dbPassword, keyFilePath)
-
-			if err != nil {
-				fmt.Printf("Warning: Failed to open database '%s': %v, skipping.\n", dbName, err)
-				continue
-			}
-
-			totalDBsSearched++
-
-			var results []gokeepasslib.Entry
-			if targetGroup != "" {
-				group := FindRootGroupByName(externalDB.Content.Root.Groups, targetGroup)
-				if group != nil {
-					results = searchEntriesInGroup(group, query, caseSensitive, exactMatch)
+				// Add database context to results. Values are printed as written, since
+				// expanding a reference could print another entry's password.
+				for _, entry := range results {
+					if !matchTags(entryTags(entry), tags, anyTag) {
+						continue
+					}
+					allResults = append(allResults, SearchResult{
+						Entry:        entry,
+						DatabaseName: dbName,
+						DatabasePath: dbPath,
+					})
 				}
-			} else {
-				results = fuzzySearchEntries(externalDB, query, caseSensitive, exactMatch)
 			}
-
-			// Add database context to results
-			for _, entry := range results {
-				allResults = append(allResults, SearchResult{
-					Entry:        entry,
-					DatabaseName: dbName,
-					DatabasePath: dbPath,
-				})
+		} else {
+			indexes := loadSearchIndexes(gokpDB, gokpKDBX, targetDatabase, os.Stdout)
+
+			for _, indexed := range indexes {
+				totalDBsSearched++
+				for _, entry := range searchIndexEntries(indexed.Index, query, caseSensitive, exactMatch, targetGroup) {
+					if !matchTags(entry.Tags, tags, anyTag) {
+						continue
+					}
+					allResults = append(allResults, SearchResult{
+						Entry:        entry.toEntry(),
+						DatabaseName: indexed.Name,
+						DatabasePath: indexed.Path,
+					})
+				}
 			}
 		}
 
 		if totalDBsSearched == 0 {
-			fmt.Println("No accessible external databases found.")
-			return
+			return newError(ErrNoDatabases, "no accessible external databases found")
+		}
+
+		criteria := fmt.Sprintf("'%s'", query)
+		if len(tags) > 0 {
+			joiner := " and "
+			if anyTag {
+				joiner = " or "
+			}
+			criteria = fmt.Sprintf("tags %s", strings.Join(tags, joiner))
+			if query != "" {
+				criteria = fmt.Sprintf("'%s' with %s", query, criteria)
+			}
 		}
 
 		if len(allResults) == 0 {
-			fmt.Printf("No entries found matching '%s' in %d database(s).\n", query, totalDBsSearched)
-			return
+			return newError(ErrNotFound, "no entries found matching %s in %d database(s)", criteria, totalDBsSearched)
 		}
 
 		selections := map[string]SearchResult{}
-		fmt.Printf("Found %d entries matching '%s' across %d database(s):\n", len(allResults), query, totalDBsSearched)
+		fmt.Printf("Found %d entries matching %s across %d database(s):\n", len(allResults), criteria, totalDBsSearched)
 		for count, result := range allResults {
 			count++
 			countStr := strconv.Itoa(count)
 			if err != nil {
 				fmt.Printf("Error converting string to int: %v\n", err)
-				return
+				return nil
 			}
 			entry := result.Entry
 			selections[countStr] = result
 			printSearchResult(countStr, entry, result.DatabaseName)
 		}
 		if setFavorites {
-			result, err := selectFavoriteEntry(selections)
-			if err != nil {
-				fmt.Printf("\nError: %v. Please try again.\n", err)
-				return
-			}
-			fmt.Printf("\nSelected entry: %s (UUID: %x, DB: %s)\n", result.Entry.GetTitle(), result.Entry.UUID, result.DatabaseName)
-			err = addFavoriteEntryToGoKP(gokpDB, result)
-			if err != nil {
-				fmt.Printf("\nError adding entry to favorites: %v\n", err)
-				return
-			}
-			fmt.Println("Entry added to favorites successfully.")
-			saveKeepassDB(gokpDB, gokpKDBX)
+			return pinSelectedFavorite(gokpDB, gokpKDBX, selections, opened)
 		}
+		return nil
 	},
 }
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSearchPrintsReferencesUnexpanded(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	target := newTestEntry("Title", "db", "Password", "s3cret")
+	ref := fmt.Sprintf("{REF:P@I:%x}", target.UUID)
+	leaky := newTestEntry("Title", "app", "URL", "https://{PASSWORD}@example.com", "Notes", "password is "+ref, "Password", "app-password")
+	leaky.Values = append(leaky.Values, mkValue("Backup", ref))
+	db := newTestDatabase("prod-password", newTestGroup("Root", target, leaky))
+	registerTestDatabase(t, gokpKDBX, "prod", "prod-password", db)
+
+	for _, args := range [][]string{{"search", "app"}, {"search", "--no-index", "app"}} {
+		var err error
+		output := captureStdout(t, func() { err = runGoKP(t, args...) })
+		if err != nil {
+			t.Fatalf("%s: %v", strings.Join(args, " "), err)
+		}
+		if !strings.Contains(output, "password is "+ref) {
+			t.Errorf("%s: notes are not printed as written:\n%s", strings.Join(args, " "), output)
+		}
+		for _, secret := range []string{"s3cret", "app-password"} {
+			if strings.Contains(output, secret) {
+				t.Errorf("%s: output contains password %q:\n%s", strings.Join(args, " "), secret, output)
+			}
+		}
+	}
+}
+
+func TestSearchMatchesCustomFieldsWithAndWithoutIndex(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	entry := newTestEntry("Title", "app", "Password", "app-password")
+	entry.Values = append(entry.Values, mkValue("Tenant", "acme-tenant"), mkProtectedValue("Recovery", "recovery-code"))
+	db := newTestDatabase("prod-password", newTestGroup("Root", entry, newTestEntry("Title", "other")))
+	registerTestDatabase(t, gokpKDBX, "prod", "prod-password", db)
+
+	for _, args := range [][]string{{"search", "acme"}, {"search", "--no-index", "acme"}} {
+		var err error
+		output := captureStdout(t, func() { err = runGoKP(t, args...) })
+		if err != nil {
+			t.Fatalf("%s: %v", strings.Join(args, " "), err)
+		}
+		if !strings.Contains(output, "Found 1 entries") || !strings.Contains(output, "- Tenant: acme-tenant") {
+			t.Errorf("%s: custom field value not matched or shown:\n%s", strings.Join(args, " "), output)
+		}
+		if !strings.Contains(output, "- Recovery: ********") || strings.Contains(output, "recovery-code") {
+			t.Errorf("%s: protected field not masked:\n%s", strings.Join(args, " "), output)
+		}
+	}
+
+	// Protected values are only searched when the databases are decrypted
+	if err := runGoKP(t, "search", "recovery-code"); err == nil {
+		t.Error("indexed search matched a protected field value")
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -15,9 +16,61 @@ type SearchResult struct {
 	DatabasePath string
 }
 
+// ExternalDatabase is an opened external database along with its registry details
+type ExternalDatabase struct {
+	Name string
+	Path string
+	DB   *gokeepasslib.Database
+}
+
+// openExternalDatabases opens every database registered in the GoKP "databases" group, or only
+// the one named target if set. Databases that can't be opened are reported to warn and skipped.
+func openExternalDatabases(gokpDB *gokeepasslib.Database, target string, warn io.Writer) []ExternalDatabase {
+	databasesGroup := FindRootGroupByName(gokpDB.Content.Root.Groups, databasesGroupName)
+	if databasesGroup == nil {
+		return nil
+	}
+
+	var opened []ExternalDatabase
+	for _, dbEntry := range databasesGroup.Entries {
+		dbName := dbEntry.GetTitle()
+
+		// If filter for specific database is set, skip all that don't match
+		if target != "" && dbName != target {
+			continue
+		}
+
+		dbPath := getEntryAttribute(&dbEntry, attrDatabasePath)
+		dbPassword := dbEntry.GetPassword()
+		keyFilePath := getEntryAttribute(&dbEntry, attrKeyFilePath)
+
+		if dbPath == "" {
+			fmt.Fprintf(warn, "Warning: Database '%s' has no path configured, skipping.\n", dbName)
+			continue
+		}
+
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			fmt.Fprintf(warn, "Warning: Database file '%s' not found for database '%s', skipping.\n", dbPath, dbName)
+			continue
+		}
+
+		externalDB, err := openExternalKeepassDB(dbPath, dbPassword, keyFilePath)
+		if err != nil {
+			fmt.Fprintf(warn, "Warning: Failed to open database '%s': %v, skipping.\n", dbName, err)
+			continue
+		}
+
+		opened = append(opened, ExternalDatabase{Name: dbName, Path: dbPath, DB: externalDB})
+	}
+	return opened
+}
+
 // openExternalKeepassDB opens an external KeePass database with credentials and optional key file
 func openExternalKeepassDB(dbPath, password, keyFilePath string) (*gokeepasslib.Database, error) {
 	file, err := os.Open(dbPath)
+	if os.IsNotExist(err) {
+		return nil, newError(ErrNotFound, "database file '%s' not found", dbPath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database file '%s': %w", dbPath, err)
 	}
@@ -29,12 +82,17 @@ func openExternalKeepassDB(dbPath, password, keyFilePath string) (*gokeepasslib.
 	if keyFilePath != "" {
 		// Check if key file exists
 		if _, err := os.Stat(keyFilePath); err != nil {
-			return nil, fmt.Errorf("key file '%s' not found: %w", keyFilePath, err)
+			return nil, keyFileError(keyFilePath, err)
+		}
+		// External databases are registered in the gokp database in the home folder
+		gokpFolder, _, _ := pathSelection(false)
+		if err := checkSecretFilePermissions(keyFilePath, gokpFolder); err != nil {
+			return nil, err
 		}
 		// Use both password and key file
 		credentials, err := gokeepasslib.NewPasswordAndKeyCredentials(password, keyFilePath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create credentials with key file: %w", err)
+			return nil, keyFileError(keyFilePath, err)
 		}
 		db.Credentials = credentials
 	} else {
@@ -42,9 +100,8 @@ func openExternalKeepassDB(dbPath, password, keyFilePath string) (*gokeepasslib.
 		db.Credentials = gokeepasslib.NewPasswordCredentials(password)
 	}
 
-	err = gokeepasslib.NewDecoder(file).Decode(db)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode database '%s': %w", dbPath, err)
+	if err := decodeKDBX(file, db, keyFilePath != ""); err != nil {
+		return nil, fmt.Errorf("cannot open database '%s': %w", dbPath, err)
 	}
 
 	if err := db.UnlockProtectedEntries(); err != nil {
@@ -54,6 +111,45 @@ func openExternalKeepassDB(dbPath, password, keyFilePath string) (*gokeepasslib.
 	return db, nil
 }
 
+// openRegisteredDatabase opens the external database registered under name in the GoKP database
+func openRegisteredDatabase(gokpDB *gokeepasslib.Database, name string) (*gokeepasslib.Database, error) {
+	dbEntry := readEntryFromGroup(gokpDB, databasesGroupName, name)
+	if dbEntry == nil {
+		return nil, newError(ErrNotFound, "database '%s' is not registered", name)
+	}
+	dbPath := getEntryAttribute(dbEntry, attrDatabasePath)
+	if dbPath == "" {
+		return nil, fmt.Errorf("database '%s' has no path configured", name)
+	}
+	return openExternalKeepassDB(dbPath, dbEntry.GetPassword(), getEntryAttribute(dbEntry, attrKeyFilePath))
+}
+
+// findEntryByUUID returns the entry with the given hex encoded UUID, searching all groups
+func findEntryByUUID(db *gokeepasslib.Database, uuid string) *gokeepasslib.Entry {
+	var found *gokeepasslib.Entry
+	forEachEntry(db.Content.Root.Groups, nil, func(_ []string, entry *gokeepasslib.Entry) {
+		if found == nil && strings.EqualFold(fmt.Sprintf("%x", entry.UUID), uuid) {
+			found = entry
+		}
+	})
+	return found
+}
+
+// saveExternalKeepassDB writes an external database back to dbPath atomically, keeping the
+// file's existing permissions
+func saveExternalKeepassDB(db *gokeepasslib.Database, dbPath string) error {
+	mode := gokpFileMode
+	if info, err := os.Stat(dbPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	// Protected values are encrypted while encoding; unlock them again so callers can keep
+	// reading the database, e.g. to refresh favorites after saving
+	db.LockProtectedEntries()
+	defer db.UnlockProtectedEntries()
+	return replaceKeepassFile(db, dbPath, mode)
+}
+
 // fuzzySearchEntries performs fuzzy search across all groups and entries
 func fuzzySearchEntries(db *gokeepasslib.Database, query string, caseSensitive bool, exactMatch bool) []gokeepasslib.Entry {
 	var results []gokeepasslib.Entry
@@ -88,6 +184,18 @@ func searchEntriesInGroup(group *gokeepasslib.Group, query string, caseSensitive
 	return results
 }
 
+// forEachEntry calls fn for every entry in groups and their subgroups, along with
+// the names of the groups leading to it
+func forEachEntry(groups []gokeepasslib.Group, path []string, fn func(groupPath []string, entry *gokeepasslib.Entry)) {
+	for i := range groups {
+		groupPath := append(append([]string{}, path...), groups[i].Name)
+		for j := range groups[i].Entries {
+			fn(groupPath, &groups[i].Entries[j])
+		}
+		forEachEntry(groups[i].Groups, groupPath, fn)
+	}
+}
+
 // fuzzyMatch performs fuzzy matching on entry fields
 func fuzzyMatch(entry gokeepasslib.Entry, query string, caseSensitive bool, exactMatch bool) bool {
 	// Get searchable fields
@@ -197,6 +305,9 @@ func printSearchResult(count string, entry gokeepasslib.Entry, databaseName stri
 		fmt.Printf("Selection: %s\n", count)
 		fmt.Printf("--------------------\n")
 	}
+	if warning := expiryWarning(entry); warning != "" {
+		fmt.Println(warning)
+	}
 	fmt.Printf("Title:     %s\n", title)
 	fmt.Printf("Database:  %s\n", databaseName)
 	if username != "" {
@@ -213,6 +324,12 @@ func printSearchResult(count string, entry gokeepasslib.Entry, databaseName stri
 		}
 	}
 	fmt.Printf(("UUID:      %x\n"), uuid)
+	if len(entry.Binaries) > 0 {
+		fmt.Printf("Attached:  %s\n", strings.Join(attachmentNames(entry), ", "))
+	}
+	if tags := entryTags(entry); len(tags) > 0 {
+		fmt.Printf("Tags:      %s\n", strings.Join(tags, ", "))
+	}
 
 	var firstPass bool = true
 	for _, value := range entry.Values {
@@ -221,9 +338,44 @@ func printSearchResult(count string, entry gokeepasslib.Entry, databaseName stri
 				fmt.Println("Custom Attributes:")
 				firstPass = false
 			}
-			fmt.Printf("- %s: %s\n", value.Key, value.Value.Content)
+			if value.Value.Protected.Bool {
+				fmt.Printf("- %s: ********\n", value.Key)
+			} else {
+				fmt.Printf("- %s: %s\n", value.Key, value.Value.Content)
+			}
+		}
+	}
+}
+
+// pinSelectedFavorite asks which of the printed results to add to the favorites and saves
+// it. Printed results hold no secrets or expanded references, so the full entry is loaded
+// from its database, taken from opened if the search already decrypted it.
+func pinSelectedFavorite(gokpDB *gokeepasslib.Database, gokpKDBX string, selections map[string]SearchResult, opened map[string]*gokeepasslib.Database) error {
+	result, err := selectFavoriteEntry(selections)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nSelected entry: %s (UUID: %x, DB: %s)\n", result.Entry.GetTitle(), result.Entry.UUID, result.DatabaseName)
+	externalDB := opened[result.DatabaseName]
+	if externalDB == nil {
+		externalDB, err = openRegisteredDatabase(gokpDB, result.DatabaseName)
+		if err != nil {
+			return fmt.Errorf("failed to open database '%s': %w", result.DatabaseName, err)
 		}
 	}
+	entry := findEntryByUUID(externalDB, fmt.Sprintf("%x", result.Entry.UUID))
+	if entry == nil {
+		return newError(ErrNotFound, "entry no longer exists in '%s', run 'gokp manage update'", result.DatabaseName)
+	}
+	result.Entry = resolvedEntry(externalDB, *entry)
+	if err := addFavoriteEntryToGoKP(gokpDB, result); err != nil {
+		return fmt.Errorf("failed to add entry to favorites: %w", err)
+	}
+	if err := saveKeepassDB(gokpDB, gokpKDBX); err != nil {
+		return fmt.Errorf("failed to save GoKP database: %w", err)
+	}
+	fmt.Println("Entry added to favorites successfully.")
+	return nil
 }
 
 func selectFavoriteEntry(selections map[string]SearchResult) (SearchResult, error) {
@@ -233,19 +385,22 @@ func selectFavoriteEntry(selections map[string]SearchResult) (SearchResult, erro
 		fmt.Printf("\n%s: %s (UUID: %x, DB: %s)", selector, result.Entry.GetTitle(), result.Entry.UUID, result.DatabaseName)
 	}
 
+	if err := requireInput("A favorite selection"); err != nil {
+		return SearchResult{}, err
+	}
 	fmt.Print("\n\nEntry number for entry to save:\n> ")
 	var selected string
 	fmt.Scanln(&selected)
 
 	if selected == "" {
 		// fmt.Println("\nNo input provided, exiting.")
-		return SearchResult{}, fmt.Errorf("no input provided")
+		return SearchResult{}, newError(ErrUsage, "no selection entered")
 	}
 
 	result := selections[selected]
 	if result.Entry.GetTitle() == "" {
 		// fmt.Printf("\nNo entry found for selection '%s'. Please try again.\n", selected)
-		return SearchResult{}, fmt.Errorf("no entry found for selection '%s'", selected)
+		return SearchResult{}, newError(ErrNotFound, "no entry found for selection '%s'", selected)
 	}
 
 	return result, nil
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"syscall"
@@ -20,6 +19,7 @@ func init() {
 	var TestMode bool
 	setupInitCmd.PersistentFlags().BoolVarP(&TestMode, "test", "t", false, "Run CLI command in test mode")
 	setupDeleteCmd.PersistentFlags().BoolVarP(&TestMode, "test", "t", false, "Run CLI command in test mode")
+	setupInitCmd.Flags().BoolP("force", "f", false, "Replace an existing app database without asking")
 	setupDeleteCmd.Flags().BoolP("force", "f", false, "Force deletion without confirmation prompts")
 }
 
@@ -31,8 +31,9 @@ var setupCmd = &cobra.Command{
 var setupInitCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initial setup of gokp app database",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		test, _ := cmd.Flags().GetBool("test")
+		force, _ := cmd.Flags().GetBool("force")
 
 		gokpFolder, _, gokpKDBX := pathSelection(test)
 		// println(gokpFolder)
@@ -41,10 +42,15 @@ var setupInitCmd = &cobra.Command{
 
 		if _, err := os.Stat(gokpFolder); os.IsNotExist(err) {
 			println("Creating .gokp folder in home directory")
-			os.Mkdir(gokpFolder, os.ModePerm)
+			if err := os.Mkdir(gokpFolder, gokpDirMode); err != nil {
+				return err
+			}
 		}
 
-		if _, err := os.Stat(gokpKDBX); !os.IsNotExist(err) {
+		if _, err := os.Stat(gokpKDBX); !os.IsNotExist(err) && !force {
+			if err := requireInput("Confirmation to replace the existing app database (or --force)"); err != nil {
+				return err
+			}
 			fmt.Print("\nWARNING: If an app database already exists, this process will delete it and create a fresh one.\nProceed (yes/no)? ")
 
 			var confirmation string
@@ -52,42 +58,52 @@ var setupInitCmd = &cobra.Command{
 
 			if confirmation != "yes" {
 				print("END: gokp setup cancelled")
-				os.Exit(0)
+				return nil
 			}
 		}
 
-		if _, err := os.Stat(gokpKDBX); !os.IsNotExist(err) {
-			err := os.Remove(gokpKDBX)
-			if err != nil {
-				log.Fatal(err)
-			}
-		}
+		// An existing app database is only replaced once the new one is written, so a missing
+		// or invalid password leaves it untouched
 		println("\nSTEP 1: Create gokp app database.")
-		fmt.Print("Enter admin password: ")
-		password, _ := term.ReadPassword(int(syscall.Stdin))
-		if string(password) == "" {
-			fmt.Println("\nPassword is required")
-			os.Exit(1)
+		passwordStr, supplied, err := passwordFromInput()
+		if err != nil {
+			return err
+		}
+		if !supplied {
+			if err := requireInput("The admin password"); err != nil {
+				return err
+			}
+			fmt.Print("Enter admin password: ")
+			password, _ := term.ReadPassword(int(syscall.Stdin))
+			if string(password) == "" {
+				fmt.Println()
+				return newError(ErrUsage, "password is required")
+			}
+			fmt.Println()
+			passwordStr = string(password)
 		}
-		fmt.Println()
-		passwordStr := string(password)
-
-		fmt.Print("\nWould you like to save this password to the local OS key store (yes/no)? ")
-		var confirmation string
-		fmt.Scanln(&confirmation)
-		if confirmation == "yes" {
-			save_password("gokp", "local", passwordStr)
-			println("Saved gokeepass password to keystore")
+
+		if interactive() {
+			fmt.Print("\nWould you like to save this password to the local OS key store (yes/no)? ")
+			var confirmation string
+			fmt.Scanln(&confirmation)
+			if confirmation == "yes" {
+				if err := save_password("gokp", "local", passwordStr); err != nil {
+					fmt.Printf("Warning: Failed to save password to keystore: %v\n", err)
+				} else {
+					println("Saved gokeepass password to keystore")
+				}
+			}
+		} else {
+			fmt.Println("Not saving the password to the OS keystore without prompts, run `gokp auth login` to save it.")
 		}
 
 		fmt.Printf("\nCreating default config.json in %s\n", gokpFolder)
-		config := createDefaultConfig()
-		if config == nil {
-			configPath := filepath.Join(gokpFolder, "config.json")
-			log.Fatalf("ERROR: Failed to create default config at %s\n", configPath)
+		if _, err := createDefaultConfig(test); err != nil {
+			return err
 		}
 
-		createDB(gokpKDBX, passwordStr)
+		return createDB(gokpKDBX, passwordStr)
 	},
 }
 
@@ -98,7 +114,7 @@ var setupDeleteCmd = &cobra.Command{
 
 WARNING: This will permanently delete your gokp database and all stored database entries.
 Make sure to backup any important data before proceeding.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		test, _ := cmd.Flags().GetBool("test")
 		force, _ := cmd.Flags().GetBool("force")
 
@@ -107,10 +123,13 @@ Make sure to backup any important data before proceeding.`,
 		// Check if database exists
 		if _, err := os.Stat(gokpKDBX); os.IsNotExist(err) {
 			fmt.Println("No gokp database found to delete.")
-			return
+			return nil
 		}
 
 		if !force {
+			if err := requireInput("Confirmation to delete the app database (or --force)"); err != nil {
+				return err
+			}
 			fmt.Printf("WARNING: This will permanently delete your gokp database at:\n%s\n", gokpKDBX)
 			fmt.Print("\nAre you sure you want to proceed? (yes/no): ")
 
@@ -119,14 +138,14 @@ Make sure to backup any important data before proceeding.`,
 
 			if confirmation != "yes" {
 				fmt.Println("Deletion cancelled.")
-				return
+				return nil
 			}
 		}
 
 		// Delete the database file
 		err := os.Remove(gokpKDBX)
 		if err != nil {
-			log.Fatalf("Failed to delete database file: %v", err)
+			return fmt.Errorf("failed to delete database file: %w", err)
 		}
 
 		fmt.Printf("Successfully deleted gokp database: %s\n", gokpKDBX)
@@ -134,6 +153,9 @@ Make sure to backup any important data before proceeding.`,
 		// Handle keystore password removal
 		removePassword := force
 		if !force {
+			if err := requireInput("Confirmation to remove the stored password (or --force)"); err != nil {
+				return err
+			}
 			fmt.Print("\nWould you like to remove the stored password from keystore as well? (yes/no): ")
 			var response string
 			fmt.Scanln(&response)
@@ -141,8 +163,11 @@ Make sure to backup any important data before proceeding.`,
 		}
 
 		if removePassword {
-			delete_password("gokp", "local")
-			fmt.Println("Password removed from keystore.")
+			if err := delete_password("gokp", "local"); err != nil {
+				fmt.Printf("Warning: Failed to remove password from keystore: %v\n", err)
+			} else {
+				fmt.Println("Password removed from keystore.")
+			}
 		}
 
 		// Handle config.json removal
@@ -151,14 +176,22 @@ Make sure to backup any important data before proceeding.`,
 		if _, err := os.Stat(configPath); os.IsNotExist(err) {
 			fmt.Println("No config.json found to delete.")
 		} else {
-			fmt.Printf("\nWould you like to remove config.json from %s? (yes/no): ", configPath)
-			var response string
-			fmt.Scanln(&response)
-			_, err := os.ReadFile(gokpFolder)
-			if err != nil {
-				fmt.Printf("Warning: Failed to remove %s: %v\n", configPath, err)
-			} else {
-				fmt.Printf("Removed: %s\n", configPath)
+			removeConfig := force
+			if !force {
+				if err := requireInput("Confirmation to remove config.json (or --force)"); err != nil {
+					return err
+				}
+				fmt.Printf("\nWould you like to remove config.json from %s? (yes/no): ", configPath)
+				var response string
+				fmt.Scanln(&response)
+				removeConfig = (response == "yes")
+			}
+			if removeConfig {
+				if err := os.Remove(configPath); err != nil {
+					fmt.Printf("Warning: Failed to remove %s: %v\n", configPath, err)
+				} else {
+					fmt.Printf("Removed: %s\n", configPath)
+				}
 			}
 		}
 
@@ -167,6 +200,9 @@ Make sure to backup any important data before proceeding.`,
 		if err == nil && len(entries) == 0 {
 			removeFolder := force
 			if !force {
+				if err := requireInput("Confirmation to remove the .gokp folder (or --force)"); err != nil {
+					return err
+				}
 				fmt.Print("\nThe .gokp folder is now empty. Remove it as well? (yes/no): ")
 				var response string
 				fmt.Scanln(&response)
@@ -184,6 +220,7 @@ Make sure to backup any important data before proceeding.`,
 		}
 
 		fmt.Println("\nGokp database deletion completed.")
+		return nil
 	},
 }
 
@@ -213,15 +250,15 @@ func mkProtectedValue(key string, value string) gokeepasslib.ValueData {
 	}
 }
 
-func createDB(dbPath string, password string) {
+func createDB(dbPath string, password string) error {
 	// file, _ := os.Create(dbPath)
 	// defer file.Close()
 
 	dbsGroup := gokeepasslib.NewGroup()
-	dbsGroup.Name = "databases"
+	dbsGroup.Name = databasesGroupName
 
 	favGroup := gokeepasslib.NewGroup()
-	favGroup.Name = "favorites"
+	favGroup.Name = favoritesGroupName
 
 	db := &gokeepasslib.Database{
 		Header:      gokeepasslib.NewHeader(),
@@ -234,17 +271,19 @@ func createDB(dbPath string, password string) {
 		},
 	}
 
-	err := FindRootGroupByName(db.Content.Root.Groups, dbsGroup.Name)
-	if err == nil {
-		log.Fatalf("ERROR: Failed to find root group by name: %s", dbsGroup.Name)
-	}
-	err = FindRootGroupByName(db.Content.Root.Groups, favGroup.Name)
-	if err == nil {
-		log.Fatalf("ERROR: Failed to find root group by name: %s", favGroup.Name)
+	setDBSchemaVersion(db, schemaVersion)
+
+	for _, name := range []string{dbsGroup.Name, favGroup.Name} {
+		if FindRootGroupByName(db.Content.Root.Groups, name) == nil {
+			return fmt.Errorf("failed to find root group by name: %s", name)
+		}
 	}
 
-	saveKeepassDB(db, dbPath)
+	if err := saveKeepassDB(db, dbPath); err != nil {
+		return fmt.Errorf("failed to save gokp app database: %w", err)
+	}
 	println("\nDONE: gokp app database created.\n\nFor information on setting up external keypass entrys: `gokp manage --help`")
+	return nil
 }
 
 func FindRootGroupByName(groups []gokeepasslib.Group, name string) *gokeepasslib.Group {
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetupInitKeepsDatabaseWithoutPassword(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	before, err := os.ReadFile(gokpKDBX)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emptyFile := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(emptyFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		args []string
+		want error
+	}{
+		{"no password source", []string{"setup", "init", "--force", "--no-input"}, ErrInputRequired},
+		{"empty password file", []string{"setup", "init", "--force", "--password-file", emptyFile}, nil},
+		{"missing password file", []string{"setup", "init", "--force", "--password-file", emptyFile + ".missing"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runGoKP(t, tt.args...)
+			if err == nil {
+				t.Fatal("setup init succeeded, want an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+			after, err := os.ReadFile(gokpKDBX)
+			if err != nil {
+				t.Fatalf("app database was removed: %v", err)
+			}
+			if string(after) != string(before) {
+				t.Error("app database was changed")
+			}
+		})
+	}
+}
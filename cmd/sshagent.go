@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf16"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func init() {
+	rootCmd.AddCommand(sshAddCmd)
+	rootCmd.AddCommand(sshAgentCmd)
+	sshAddCmd.Flags().DurationP("lifetime", "l", 0, "Remove the key from the agent after this long (e.g. 1h, default: never)")
+	sshAgentCmd.Flags().StringP("socket", "s", "", "Unix socket to listen on (default: agent.sock in the .gokp folder)")
+	sshAgentCmd.Flags().DurationP("lifetime", "l", 0, "Stop the agent after this long (e.g. 8h, default: never)")
+}
+
+// keeAgentSettingsName is the attachment KeeAgent stores its per-entry settings in
+const keeAgentSettingsName = "KeeAgent.settings"
+
+var sshAddCmd = &cobra.Command{
+	Use:   "ssh-add ENTRY...",
+	Short: "Load SSH keys stored as entry attachments into the running ssh-agent",
+	Long: `Load SSH private keys stored as entry attachments into the ssh-agent at SSH_AUTH_SOCK.
+
+The key attachment is taken from the entry's KeeAgent settings when present,
+otherwise the first attachment holding a private key is used. Encrypted keys are
+decrypted with the entry password.
+
+Entries:
+  DATABASE/[GROUP/...]TITLE   Entry in a registered database
+  fav:INDEX                   Favorite by index
+
+Examples:
+  gokp ssh-add prod/servers/bastion
+  gokp ssh-add fav:2 --lifetime 1h`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lifetime, _ := cmd.Flags().GetDuration("lifetime")
+
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return errors.New("SSH_AUTH_SOCK is not set, start an ssh-agent (or `gokp ssh-agent`) first")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return fmt.Errorf("failed to connect to ssh-agent: %w", err)
+		}
+		defer conn.Close()
+		client := agent.NewClient(conn)
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		resolver := newSecretResolver(gokpDB)
+		for _, ref := range args {
+			db, entry, err := resolver.resolveEntry(ref)
+			if err != nil {
+				return fmt.Errorf("failed to find entry: %w", err)
+			}
+			key, err := entrySSHKey(db, entry)
+			if err != nil {
+				return fmt.Errorf("failed to load key from '%s': %w", ref, err)
+			}
+			err = client.Add(agent.AddedKey{
+				PrivateKey:   key,
+				Comment:      entry.GetTitle(),
+				LifetimeSecs: uint32(lifetime.Seconds()),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to add key to ssh-agent: %w", err)
+			}
+			fmt.Printf("Identity added: %s\n", entry.GetTitle())
+		}
+		return nil
+	},
+}
+
+var sshAgentCmd = &cobra.Command{
+	Use:   "ssh-agent [ENTRY...]",
+	Short: "Run an ssh-agent serving keys from the external databases",
+	Long: `Run an ssh-agent serving SSH keys stored as entry attachments.
+
+With no entries, loads every entry whose KeeAgent settings allow use of its SSH key
+and add it when the database opens. The agent listens on a unix socket readable only
+by the current user and prints the SSH_AUTH_SOCK to use. It stops on Ctrl+C, SIGTERM
+or when --lifetime elapses.
+
+Examples:
+  gokp ssh-agent --lifetime 8h
+  gokp ssh-agent prod/servers/bastion fav:2 --socket /tmp/gokp-agent.sock`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socket, _ := cmd.Flags().GetString("socket")
+		lifetime, _ := cmd.Flags().GetDuration("lifetime")
+
+		gokpFolder, _, gokpKDBX := pathSelection(false)
+		if socket == "" {
+			socket = filepath.Join(gokpFolder, "agent.sock")
+		}
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		keyring := agent.NewKeyring()
+		added := 0
+		addKey := func(db *gokeepasslib.Database, entry *gokeepasslib.Entry) {
+			key, err := entrySSHKey(db, entry)
+			if err != nil {
+				fmt.Printf("Warning: Failed to load key from '%s': %v, skipping.\n", entry.GetTitle(), err)
+				return
+			}
+			if err := keyring.Add(agent.AddedKey{PrivateKey: key, Comment: entry.GetTitle()}); err != nil {
+				fmt.Printf("Warning: Failed to add key from '%s': %v, skipping.\n", entry.GetTitle(), err)
+				return
+			}
+			added++
+			fmt.Printf("Identity added: %s\n", entry.GetTitle())
+		}
+
+		if len(args) > 0 {
+			resolver := newSecretResolver(gokpDB)
+			for _, ref := range args {
+				db, entry, err := resolver.resolveEntry(ref)
+				if err != nil {
+					return fmt.Errorf("failed to find entry: %w", err)
+				}
+				addKey(db, entry)
+			}
+		} else {
+			for _, external := range openExternalDatabases(gokpDB, "", os.Stdout) {
+				forEachEntry(external.DB.Content.Root.Groups, nil, func(_ []string, entry *gokeepasslib.Entry) {
+					if settings, err := readKeeAgentSettings(external.DB, entry); err == nil && settings.AllowUseOfSshKey && settings.AddAtDatabaseOpen {
+						addKey(external.DB, entry)
+					}
+				})
+			}
+		}
+		closeKeepassDB(gokpDB)
+
+		if added == 0 {
+			return newError(ErrNotFound, "no SSH keys found to serve")
+		}
+
+		if err := serveSSHAgent(keyring, socket, lifetime); err != nil {
+			return fmt.Errorf("ssh-agent failed: %w", err)
+		}
+		return nil
+	},
+}
+
+// serveSSHAgent listens on socket until interrupted or lifetime elapses
+func serveSSHAgent(keyring agent.Agent, socket string, lifetime time.Duration) error {
+	if _, err := os.Stat(socket); err == nil {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			conn.Close()
+			return fmt.Errorf("an agent is already listening on %s", socket)
+		}
+		os.Remove(socket)
+	}
+
+	listener, err := listenPrivateSocket(socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socket)
+
+	fmt.Printf("\nSSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", socket)
+	fmt.Printf("echo Agent pid %d;\n", os.Getpid())
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	var expired <-chan time.Time
+	if lifetime > 0 {
+		expired = time.After(lifetime)
+	}
+	go func() {
+		select {
+		case <-sigChan:
+		case <-expired:
+			fmt.Println("Agent lifetime elapsed, stopping.")
+		}
+		keyring.RemoveAll()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			agent.ServeAgent(keyring, conn)
+		}()
+	}
+}
+
+// keeAgentSettings is the subset of the KeeAgent plugin's entry settings gokp honors
+type keeAgentSettings struct {
+	AllowUseOfSshKey  bool `xml:"AllowUseOfSshKey"`
+	AddAtDatabaseOpen bool `xml:"AddAtDatabaseOpen"`
+	Location          struct {
+		SelectedType   string `xml:"SelectedType"`
+		AttachmentName string `xml:"AttachmentName"`
+		FileName       string `xml:"FileName"`
+	} `xml:"Location"`
+}
+
+func readKeeAgentSettings(db *gokeepasslib.Database, entry *gokeepasslib.Entry) (*keeAgentSettings, error) {
+	data, err := readEntryAttachment(db, entry, keeAgentSettingsName)
+	if err != nil {
+		return nil, err
+	}
+	// KeeAgent declares utf-16 in the XML header; the content may be either encoding
+	decoder := xml.NewDecoder(strings.NewReader(decodeXMLText(data)))
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	settings := &keeAgentSettings{}
+	if err := decoder.Decode(settings); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", keeAgentSettingsName, err)
+	}
+	return settings, nil
+}
+
+// decodeXMLText converts UTF-16 (with byte order mark) or UTF-8 bytes to a string
+func decodeXMLText(data []byte) string {
+	if len(data) >= 2 && (data[0] == 0xFF && data[1] == 0xFE || data[0] == 0xFE && data[1] == 0xFF) {
+		order := binary.ByteOrder(binary.LittleEndian)
+		if data[0] == 0xFE {
+			order = binary.BigEndian
+		}
+		units := make([]uint16, (len(data)-2)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2+i*2:])
+		}
+		return string(utf16.Decode(units))
+	}
+	return strings.TrimPrefix(string(data), "\ufeff")
+}
+
+// entrySSHKey loads and decrypts the SSH private key attached to an entry
+func entrySSHKey(db *gokeepasslib.Database, entry *gokeepasslib.Entry) (interface{}, error) {
+	if settings, err := readKeeAgentSettings(db, entry); err == nil {
+		if !settings.AllowUseOfSshKey {
+			return nil, fmt.Errorf("KeeAgent settings do not allow use of this SSH key")
+		}
+		switch settings.Location.SelectedType {
+		case "file":
+			data, err := os.ReadFile(settings.Location.FileName)
+			if err != nil {
+				return nil, err
+			}
+			return parseSSHPrivateKey(data, entry.GetPassword())
+		default:
+			data, err := readEntryAttachment(db, entry, settings.Location.AttachmentName)
+			if err != nil {
+				return nil, err
+			}
+			return parseSSHPrivateKey(data, entry.GetPassword())
+		}
+	}
+
+	for _, ref := range entry.Binaries {
+		if ref.Name == keeAgentSettingsName {
+			continue
+		}
+		data, err := readEntryAttachment(db, entry, ref.Name)
+		if err != nil {
+			continue
+		}
+		if key, err := parseSSHPrivateKey(data, entry.GetPassword()); err == nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("entry '%s' has no SSH private key attachment", entry.GetTitle())
+}
+
+// parseSSHPrivateKey parses a PEM or OpenSSH private key, using passphrase if it is encrypted
+func parseSSHPrivateKey(data []byte, passphrase string) (interface{}, error) {
+	key, err := ssh.ParseRawPrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return ssh.ParseRawPrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	return key, err
+}
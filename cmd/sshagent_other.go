@@ -0,0 +1,21 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"net"
+	"os"
+)
+
+// listenPrivateSocket creates a unix socket, restricting it to the owner where file modes apply
+func listenPrivateSocket(socket string) (net.Listener, error) {
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, gokpFileMode); err != nil && permissionsApply() {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package cmd
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenPrivateSocket creates a unix socket that only the owner can connect to. The umask
+// is narrowed while the socket is created, so it is never reachable by others, not even
+// before a chmod.
+func listenPrivateSocket(socket string) (net.Listener, error) {
+	oldMask := unix.Umask(0o177)
+	defer unix.Umask(oldMask)
+	return net.Listen("unix", socket)
+}
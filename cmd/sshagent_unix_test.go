@@ -0,0 +1,33 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestListenPrivateSocketIgnoresUmask(t *testing.T) {
+	defer unix.Umask(unix.Umask(0))
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := listenPrivateSocket(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("socket mode = %o, want 600", mode)
+	}
+	if mask := unix.Umask(0); mask != 0 {
+		t.Errorf("umask = %o after listening, want it restored to 0", mask)
+	}
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().String("with", "", "Path to the other copy of the database (required)")
+	syncCmd.Flags().StringP("key", "k", "", "Key file of the other copy (default: the registered database's key file)")
+	syncCmd.Flags().Bool("pull", false, "Only update the registered database, leave the other copy unchanged")
+	syncCmd.Flags().BoolP("dry-run", "n", false, "Show what would change without writing anything")
+	syncCmd.MarkFlagRequired("with")
+}
+
+var syncCmd = &cobra.Command{
+	Use:               "sync DATABASE --with OTHER.kdbx",
+	ValidArgsFunction: completeFirstDatabaseName,
+	Short:             "Merge a registered database with another copy of it",
+	Long: `Merge a registered database with another copy of it, like KeePass's synchronize.
+
+Entries and groups are matched by UUID and the most recently modified version wins,
+with the older version kept in the entry history. Entries changed in both copies since
+they were last in sync are reported as conflicts. Deletions in either copy are applied
+unless the object was changed after it was deleted.
+
+Both files are written with the merged result, unless --pull is given. Each file is
+replaced atomically and the sync is aborted if either file changes while merging.
+
+The other copy is opened with the registered database's credentials, or a prompted
+password if they don't match.
+
+Examples:
+  gokp sync work --with /mnt/share/work.kdbx
+  gokp sync work --with /mnt/share/work.kdbx --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		otherPath, _ := cmd.Flags().GetString("with")
+		otherKey, _ := cmd.Flags().GetString("key")
+		pull, _ := cmd.Flags().GetBool("pull")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		dbEntry := readEntryFromGroup(gokpDB, databasesGroupName, args[0])
+		if dbEntry == nil {
+			return newError(ErrNotFound, "database '%s' is not registered", args[0])
+		}
+		localPath := getEntryAttribute(dbEntry, attrDatabasePath)
+		keyFile := getEntryAttribute(dbEntry, attrKeyFilePath)
+		if otherKey == "" {
+			otherKey = keyFile
+		}
+		if sameFilePath(localPath, otherPath) {
+			return newError(ErrUsage, "the other copy is the registered database itself")
+		}
+
+		localInfo, err := os.Stat(localPath)
+		if err != nil {
+			return fmt.Errorf("failed to read database: %w", err)
+		}
+		otherInfo, err := os.Stat(otherPath)
+		if err != nil {
+			return fmt.Errorf("failed to read other copy: %w", err)
+		}
+
+		local, err := openExternalKeepassDB(localPath, dbEntry.GetPassword(), keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to open database '%s': %w", args[0], err)
+		}
+		other, err := openExternalKeepassDB(otherPath, dbEntry.GetPassword(), otherKey)
+		if err != nil {
+			password, promptErr := promptPassword(fmt.Sprintf("Password for %s: ", otherPath))
+			if promptErr != nil {
+				return fmt.Errorf("failed to open other copy: %w", err)
+			}
+			other, err = openExternalKeepassDB(otherPath, password, otherKey)
+			if err != nil {
+				return fmt.Errorf("failed to open other copy: %w", err)
+			}
+		}
+
+		report := mergeDatabases(local, other)
+		printMergeReport(args[0], report)
+		otherReport := &mergeReport{}
+		if !pull {
+			otherReport = mergeDatabases(other, local)
+		}
+
+		if dryRun {
+			fmt.Println("\nDry run, nothing was written.")
+			return nil
+		}
+
+		for path, info := range map[string]os.FileInfo{localPath: localInfo, otherPath: otherInfo} {
+			if current, err := os.Stat(path); err != nil || !current.ModTime().Equal(info.ModTime()) || current.Size() != info.Size() {
+				return newError(ErrConflict, "'%s' changed while merging, nothing was written. Run sync again", path)
+			}
+		}
+
+		if report.modified() {
+			if err := saveExternalKeepassDB(local, localPath); err != nil {
+				return fmt.Errorf("failed to save database: %w", err)
+			}
+		}
+		if otherReport.modified() {
+			if err := saveExternalKeepassDB(other, otherPath); err != nil {
+				return fmt.Errorf("failed to save other copy: %w", err)
+			}
+		}
+		fmt.Printf("\nSynced '%s' with %s (%d change(s) here, %d in the other copy).\n", args[0], otherPath, report.changes(), otherReport.changes())
+		return nil
+	},
+}
+
+func printMergeReport(name string, report *mergeReport) {
+	if report.changes() == 0 && len(report.Conflicts) == 0 {
+		fmt.Printf("'%s' is already up to date.\n", name)
+		return
+	}
+	fmt.Printf("Changes to '%s':\n", name)
+	sections := []struct {
+		marker string
+		color  string
+		items  []string
+	}{
+		{"+", ColorBoldGreen, report.Added},
+		{"~", ColorReset, report.Updated},
+		{">", ColorReset, report.Moved},
+		{"-", ColorBoldRed, report.Deleted},
+		{"!", ColorBoldRed, report.Conflicts},
+	}
+	for _, section := range sections {
+		for _, item := range section.items {
+			fmt.Printf("  %s%s%s %s\n", section.color, section.marker, ColorReset, item)
+		}
+	}
+	if len(report.Conflicts) > 0 {
+		fmt.Printf("\n%d conflict(s), the older versions of conflicting entries are kept in their history.\n", len(report.Conflicts))
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func init() {
+	rootCmd.AddCommand(tagsCmd)
+	tagsCmd.Flags().StringP("database", "d", "", "List only the tags of a specific external database")
+	tagsCmd.RegisterFlagCompletionFunc("database", completeDatabaseNames)
+}
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "List the tags in use in the external databases",
+	Long: `List the tags used by entries in the external databases, with the number of entries
+carrying each tag per database. Tags are read from the search index, so only databases
+that changed since they were last indexed are decrypted.
+
+Examples:
+  gokp tags
+  gokp tags -d work
+  gokp search --tag prod --tag shared ""`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targetDatabase, _ := cmd.Flags().GetString("database")
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		indexes := loadSearchIndexes(gokpDB, gokpKDBX, targetDatabase, os.Stdout)
+		if len(indexes) == 0 {
+			return newError(ErrNoDatabases, "no accessible external databases found")
+		}
+
+		// counts[tag][database] is the number of entries with the tag
+		counts := map[string]map[string]int{}
+		var databases []string
+		for _, indexed := range indexes {
+			databases = append(databases, indexed.Name)
+			for _, entry := range indexed.Index.Entries {
+				for _, tag := range entry.Tags {
+					if counts[tag] == nil {
+						counts[tag] = map[string]int{}
+					}
+					counts[tag][indexed.Name]++
+				}
+			}
+		}
+
+		if len(counts) == 0 {
+			fmt.Printf("No tagged entries found in %d database(s).\n", len(databases))
+			return nil
+		}
+
+		tags := make([]string, 0, len(counts))
+		for tag := range counts {
+			tags = append(tags, tag)
+		}
+		sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i]) < strings.ToLower(tags[j]) })
+
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(table, "TAG\tTOTAL\t%s\n", strings.ToUpper(strings.Join(databases, "\t")))
+		for _, tag := range tags {
+			total := 0
+			for _, count := range counts[tag] {
+				total += count
+			}
+			fmt.Fprintf(table, "%s\t%d", tag, total)
+			for _, database := range databases {
+				fmt.Fprintf(table, "\t%d", counts[tag][database])
+			}
+			fmt.Fprintln(table)
+		}
+		table.Flush()
+		return nil
+	},
+}
+
+// entryTags splits an entry's Tags field. KeePass separates tags with ';' or ','.
+func entryTags(entry gokeepasslib.Entry) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(entry.Tags, func(r rune) bool { return r == ';' || r == ',' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// matchTags reports whether tags contain all of wanted, or any of them if anyTag is set.
+// Tags compare case-insensitively, as in KeePass.
+func matchTags(tags, wanted []string, anyTag bool) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, want := range wanted {
+		found := false
+		for _, tag := range tags {
+			if strings.EqualFold(tag, want) {
+				found = true
+				break
+			}
+		}
+		if found && anyTag {
+			return true
+		}
+		if !found && !anyTag {
+			return false
+		}
+	}
+	return !anyTag
+}
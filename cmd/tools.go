@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,8 +25,8 @@ func addGoKPEntryToGroup(db *gokeepasslib.Database, groupName string, title stri
 	// entry.Values = append(entry.Values, mkValue("URL", key))
 
 	// Additional database-specific attributes
-	entry.Values = append(entry.Values, mkValue("Database Path", path))
-	entry.Values = append(entry.Values, mkValue("Key File Path", key))
+	entry.Values = append(entry.Values, mkValue(attrDatabasePath, path))
+	entry.Values = append(entry.Values, mkValue(attrKeyFilePath, key))
 	entry.Values = append(entry.Values, mkValue("Database Type", "KeePass"))
 	entry.Values = append(entry.Values, mkValue("Format", "KDBX"))
 	entry.Values = append(entry.Values, mkValue("Created Date", getCurrentTimestamp("datetime")))
@@ -41,7 +45,7 @@ func addFavoriteEntryToGoKP(db *gokeepasslib.Database, result SearchResult) erro
 	// Find favorites entry index value in root group
 	var favGroupIndex int
 	for i := range db.Content.Root.Groups {
-		if db.Content.Root.Groups[i].Name == "favorites" {
+		if db.Content.Root.Groups[i].Name == favoritesGroupName {
 			favGroupIndex = i
 			break
 		}
@@ -51,9 +55,9 @@ func addFavoriteEntryToGoKP(db *gokeepasslib.Database, result SearchResult) erro
 	for _, entry := range db.Content.Root.Groups[favGroupIndex].Entries {
 		for _, value := range entry.Values {
 			if entry.UUID == result.Entry.UUID {
-				return fmt.Errorf("entry '%s' already exists in favorites", entry.GetTitle())
+				return newError(ErrExists, "entry '%s' already exists in favorites", entry.GetTitle())
 			}
-			index := value.Key == "Favorite Index"
+			index := value.Key == attrFavoriteIndex
 			if index {
 				indexValue, err := strconv.Atoi(value.Value.Content)
 				if err != nil {
@@ -86,13 +90,15 @@ func addFavoriteEntryToGoKP(db *gokeepasslib.Database, result SearchResult) erro
 	newEntry.Values = append(newEntry.Values, mkValue("URL", url))
 
 	// Additional database-specific attributes
-	newEntry.Values = append(newEntry.Values, mkValue("Database Source", result.DatabaseName))
-	newEntry.Values = append(newEntry.Values, mkValue("Database path", result.DatabasePath))
-	newEntry.Values = append(newEntry.Values, mkValue("Database UUID", fmt.Sprintf("%x", uuid)))
-	newEntry.Values = append(newEntry.Values, mkValue("Favorite Index", strconv.Itoa(favIndex)))
+	newEntry.Values = append(newEntry.Values, mkValue(attrDatabaseSource, result.DatabaseName))
+	newEntry.Values = append(newEntry.Values, mkValue(attrDatabasePath, result.DatabasePath))
+	newEntry.Values = append(newEntry.Values, mkValue(attrDatabaseUUID, fmt.Sprintf("%x", uuid)))
+	newEntry.Values = append(newEntry.Values, mkValue(attrFavoriteIndex, strconv.Itoa(favIndex)))
 	newEntry.Values = append(newEntry.Values, mkValue("Created Date", getCurrentTimestamp("datetime")))
 	newEntry.Values = append(newEntry.Values, mkValue("Last Modified", getCurrentTimestamp("iso")))
 	newEntry.Values = append(newEntry.Values, mkValue("Notes", "Entry from external KeePass database managed by gokp"))
+	newEntry.Tags = entry.Tags
+	copyEntryExpiry(&newEntry, entry)
 
 	db.Content.Root.Groups[favGroupIndex].Entries = append(db.Content.Root.Groups[favGroupIndex].Entries, newEntry)
 	return nil
@@ -101,7 +107,7 @@ func addFavoriteEntryToGoKP(db *gokeepasslib.Database, result SearchResult) erro
 func readFavoritesEntry(db *gokeepasslib.Database, favIndex int) *gokeepasslib.Entry {
 	var favGroupIndex int
 	for i := range db.Content.Root.Groups {
-		if db.Content.Root.Groups[i].Name == "favorites" {
+		if db.Content.Root.Groups[i].Name == favoritesGroupName {
 			favGroupIndex = i
 			break
 		}
@@ -109,8 +115,7 @@ func readFavoritesEntry(db *gokeepasslib.Database, favIndex int) *gokeepasslib.E
 
 	// Loop over entries in favorites group to find existing indexes
 	for _, entry := range db.Content.Root.Groups[favGroupIndex].Entries {
-		fmt.Println(entry.GetTitle(), entry.GetContent("Favorite Index"))
-		if entry.GetContent("Favorite Index") == strconv.Itoa(favIndex) {
+		if entry.GetContent(attrFavoriteIndex) == strconv.Itoa(favIndex) {
 			return &entry
 		}
 	}
@@ -147,7 +152,41 @@ func readEntryFromGroup(db *gokeepasslib.Database, groupName string, title strin
 	return nil
 }
 
+// schemaNoteShown keeps openKeepassDB from repeating the outdated schema note within a run
+var schemaNoteShown bool
+
 func openKeepassDB(dbPath string, password string) (*gokeepasslib.Database, error) {
+	db, err := decodeKeepassDB(dbPath, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bring older app database layouts up to date in memory; they are written
+	// back on the next save or by `gokp doctor --migrate`
+	applied, err := migrateGoKPDB(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) > 0 && !schemaNoteShown {
+		schemaNoteShown = true
+		fmt.Fprintf(os.Stderr, "Note: gokp database schema is outdated, run `gokp doctor --migrate` to upgrade it on disk.\n")
+	}
+
+	return db, nil
+}
+
+// decodeKeepassDB opens and unlocks the app database without applying schema migrations
+func decodeKeepassDB(dbPath string, password string) (*gokeepasslib.Database, error) {
+	if cached := verifiedGoKPDB; cached.db != nil && cached.path == dbPath && cached.password == password {
+		verifiedGoKPDB.db = nil
+		return cached.db, nil
+	}
+
+	// config.json always sits next to the gokp.kdbx it belongs to
+	if err := checkSecretFilePermissions(dbPath, filepath.Dir(dbPath)); err != nil {
+		return nil, err
+	}
+
 	file, err := os.Open(dbPath)
 	if err != nil {
 		return nil, err
@@ -157,8 +196,7 @@ func openKeepassDB(dbPath string, password string) (*gokeepasslib.Database, erro
 	db := gokeepasslib.NewDatabase()
 	db.Credentials = gokeepasslib.NewPasswordCredentials(password)
 
-	err = gokeepasslib.NewDecoder(file).Decode(db)
-	if err != nil {
+	if err := decodeKDBX(file, db, false); err != nil {
 		return nil, err
 	}
 
@@ -175,38 +213,101 @@ func closeKeepassDB(db *gokeepasslib.Database) {
 	}
 }
 
+// saveKeepassDB writes the app database atomically with owner-only permissions. It leaves
+// the database locked.
 func saveKeepassDB(db *gokeepasslib.Database, dbPath string) error {
-	writeFile, err := os.Create(dbPath)
-	if err != nil {
-		return err
-	}
-	defer writeFile.Close()
-
 	db.LockProtectedEntries()
-	keepassEncoder := gokeepasslib.NewEncoder(writeFile)
-	if err := keepassEncoder.Encode(db); err != nil {
-		return err
-	}
+	return replaceKeepassFile(db, dbPath, gokpFileMode)
+}
 
-	return nil
+// replaceKeepassFile encodes a locked database to a temporary file in the folder of dbPath,
+// which then replaces dbPath, so a failed write or a crash never leaves a partially written
+// database behind
+func replaceKeepassFile(db *gokeepasslib.Database, dbPath string, mode os.FileMode) error {
+	return replaceFile(dbPath, mode, func(w io.Writer) error {
+		if err := gokeepasslib.NewEncoder(w).Encode(db); err != nil {
+			return fmt.Errorf("failed to encode database '%s': %w", dbPath, err)
+		}
+		return nil
+	})
 }
 
-func getGoKPPassword() (string, error) {
-	secret, err := get_password("gokp", "local")
-	if err != nil {
+// maxPasswordAttempts is how often a wrong GoKP password may be entered at the prompt
+const maxPasswordAttempts = 3
+
+// verifiedGoKPDB holds the app database decoded while checking a password in
+// getGoKPPassword, so the decodeKeepassDB call that follows doesn't derive the key again
+var verifiedGoKPDB struct {
+	path     string
+	password string
+	db       *gokeepasslib.Database
+}
+
+// getGoKPPassword returns the GoKP password given with --password-stdin, --password-file or
+// GOKP_PASSWORD_FD, else the one saved in the OS keystore, else prompts for it. Saved and
+// prompted passwords are checked against gokpKDBX; a wrong saved password falls back to the
+// prompt, and a wrong prompted one is asked for again.
+func getGoKPPassword(gokpKDBX string) (string, error) {
+	if secret, ok, err := passwordFromInput(); ok {
+		return secret, err
+	}
+	if secret, err := get_password("gokp", "local"); err == nil {
+		err := checkGoKPPassword(gokpKDBX, secret)
+		if !errors.Is(err, ErrBadCredentials) {
+			return secret, nil
+		}
+		if !interactive() {
+			return "", newError(ErrBadCredentials, "the GoKP password saved in the OS keystore is wrong, run `gokp auth login` to update it")
+		}
+		fmt.Println("The GoKP password saved in the OS keystore is wrong, run `gokp auth login` to update it.")
+	} else if !interactive() {
+		return "", newError(ErrLocked, "the GoKP password is needed, run `gokp auth login` or pass it with --password-stdin, --password-file or %s", passwordFDEnv)
+	}
+
+	for attempt := 1; ; attempt++ {
 		fmt.Print("Enter admin password: ")
 		password, err := term.ReadPassword(int(syscall.Stdin))
+		fmt.Println()
 		if err != nil {
 			return "", fmt.Errorf("failed to read password from terminal: %w", err)
 		}
 		if string(password) == "" {
-			fmt.Println("\nPassword is required")
-			os.Exit(1)
+			return "", newError(ErrLocked, "password is required")
 		}
-		fmt.Println()
-		secret = string(password)
+
+		if !errors.Is(checkGoKPPassword(gokpKDBX, string(password)), ErrBadCredentials) {
+			return string(password), nil
+		}
+		if attempt == maxPasswordAttempts {
+			return "", newError(ErrBadCredentials, "wrong password after %d attempts", maxPasswordAttempts)
+		}
+		fmt.Printf("Wrong password, try again (%d attempt(s) left).\n", maxPasswordAttempts-attempt)
+	}
+}
+
+// checkGoKPPassword decodes gokpKDBX with password, keeping the result for the next
+// decodeKeepassDB call. Other failures than a wrong password, like a missing database, are
+// left to the caller opening it.
+func checkGoKPPassword(gokpKDBX string, password string) error {
+	db, err := decodeKeepassDB(gokpKDBX, password)
+	if err == nil {
+		verifiedGoKPDB.path, verifiedGoKPDB.password, verifiedGoKPDB.db = gokpKDBX, password, db
+	}
+	return err
+}
+
+// promptPassword prints prompt and reads a password from the terminal without echoing it
+func promptPassword(prompt string) (string, error) {
+	if err := requireInput(strings.TrimSuffix(strings.TrimSpace(prompt), ":")); err != nil {
+		return "", err
+	}
+	fmt.Print(prompt)
+	password, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read password from terminal: %w", err)
 	}
-	return secret, nil
+	return string(password), nil
 }
 
 // Helper function to get any attribute value from an entry
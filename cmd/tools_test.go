@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestGetGoKPPasswordDecodesOnce(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+
+	secret, err := getGoKPPassword(gokpKDBX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := verifiedGoKPDB.db
+	if verified == nil {
+		t.Fatal("the database decoded while checking the password was not kept")
+	}
+	db, err := decodeKeepassDB(gokpKDBX, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db != verified {
+		t.Error("decodeKeepassDB decoded the database again")
+	}
+	if verifiedGoKPDB.db != nil {
+		t.Error("the kept database was not released after use")
+	}
+}
+
+func TestGetGoKPPasswordRejectsStaleKeystorePassword(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	t.Setenv(nonInteractiveEnv, "1")
+	if err := keyring.Set("gokp", "local", "stale-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := getGoKPPassword(gokpKDBX)
+	if !errors.Is(err, ErrBadCredentials) {
+		t.Fatalf("got error %v, want ErrBadCredentials", err)
+	}
+	if !strings.Contains(err.Error(), "gokp auth login") {
+		t.Errorf("error %q doesn't point to `gokp auth login`", err)
+	}
+}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func init() {
+	rootCmd.AddCommand(urlCmd)
+	urlCmd.Flags().StringP("database", "d", "", "Match only entries of a specific external database")
+	urlCmd.RegisterFlagCompletionFunc("database", completeDatabaseNames)
+	urlCmd.Flags().BoolP("favorites", "f", false, "Select entries for favorites")
+}
+
+// additionalURLKey is the field KeePassXC and KeePass2Android use for extra entry URLs,
+// numbered KP2A_URL_1, KP2A_URL_2 and so on
+const additionalURLKey = "KP2A_URL"
+
+// URL match scores. A host match outranks any subdomain match, each matching path segment
+// outranks a scheme mismatch, and the scheme only breaks ties.
+const (
+	urlScoreHost      = 1000
+	urlScoreSubdomain = 500
+	urlScorePath      = 10
+	urlScoreScheme    = 2
+)
+
+var urlCmd = &cobra.Command{
+	Use:   "url URL",
+	Short: "Find the entries whose URLs match a site",
+	Long: `Find the entries of the external databases whose URL or additional KP2A_URL fields
+match a site, best matches first.
+
+Scheme, host and port are normalized, so "Example.com", "https://example.com/" and
+"https://example.com:443" are the same site. Entries for a parent domain match its
+subdomains, and "*.example.com" matches subdomains only. Ranking:
+
+  1. entries for the exact host, before entries for a parent domain
+  2. entries whose path is the longest prefix of the URL's path
+  3. entries with the same scheme, before ones for another scheme
+
+Entries for another port never match. Like search, this uses the search index.
+
+Examples:
+  gokp url https://grafana.internal.example.com/d/abc
+  gokp url gitlab.example.com:8443 -d work
+  gokp url https://mail.example.com -f`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targetDatabase, _ := cmd.Flags().GetString("database")
+		setFavorites, _ := cmd.Flags().GetBool("favorites")
+
+		target, err := parseSiteURL(args[0])
+		if err != nil {
+			return newError(ErrUsage, "invalid URL '%s': %v", args[0], err)
+		}
+
+		_, _, gokpKDBX := pathSelection(false)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		gokpDB, err := openKeepassDB(gokpKDBX, secret)
+		if err != nil {
+			return fmt.Errorf("failed to open GoKP database: %w", err)
+		}
+
+		indexes := loadSearchIndexes(gokpDB, gokpKDBX, targetDatabase, os.Stdout)
+		if len(indexes) == 0 {
+			return newError(ErrNoDatabases, "no accessible external databases found")
+		}
+
+		type rankedResult struct {
+			SearchResult
+			score int
+		}
+		var ranked []rankedResult
+		for _, indexed := range indexes {
+			for _, entry := range indexed.Index.Entries {
+				score := 0
+				for _, entryURL := range append([]string{entry.URL}, entry.URLs...) {
+					score = max(score, urlMatchScore(entryURL, target))
+				}
+				if score > 0 {
+					ranked = append(ranked, rankedResult{SearchResult{
+						Entry:        entry.toEntry(),
+						DatabaseName: indexed.Name,
+						DatabasePath: indexed.Path,
+					}, score})
+				}
+			}
+		}
+
+		if len(ranked) == 0 {
+			return newError(ErrNotFound, "no entries found for %s in %d database(s)", target.Host, len(indexes))
+		}
+		sort.SliceStable(ranked, func(i, j int) bool {
+			if ranked[i].score != ranked[j].score {
+				return ranked[i].score > ranked[j].score
+			}
+			return strings.ToLower(ranked[i].Entry.GetTitle()) < strings.ToLower(ranked[j].Entry.GetTitle())
+		})
+
+		selections := map[string]SearchResult{}
+		fmt.Printf("Found %d entries for %s across %d database(s):\n", len(ranked), target.Host, len(indexes))
+		for i, result := range ranked {
+			countStr := strconv.Itoa(i + 1)
+			selections[countStr] = result.SearchResult
+			printSearchResult(countStr, result.Entry, result.DatabaseName)
+		}
+		if setFavorites {
+			return pinSelectedFavorite(gokpDB, gokpKDBX, selections, nil)
+		}
+		return nil
+	},
+}
+
+// siteURL is a normalized URL. Scheme and Port are empty when the URL had none.
+type siteURL struct {
+	Scheme string
+	Host   string
+	Port   string
+	Path   string
+}
+
+// parseSiteURL normalizes a URL, accepting URLs without a scheme like "example.com/login"
+func parseSiteURL(raw string) (*siteURL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("empty URL")
+	}
+	hasScheme := strings.Contains(raw, "://")
+	if !hasScheme {
+		raw = "scheme-less://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("no host")
+	}
+
+	site := &siteURL{
+		Host: strings.TrimSuffix(strings.ToLower(u.Hostname()), "."),
+		Port: u.Port(),
+		Path: strings.TrimSuffix(u.EscapedPath(), "/"),
+	}
+	if hasScheme {
+		site.Scheme = strings.ToLower(u.Scheme)
+	}
+	return site, nil
+}
+
+// effectivePort returns the explicit port, else the default port of the scheme, if known
+func (s *siteURL) effectivePort() string {
+	if s.Port != "" {
+		return s.Port
+	}
+	return map[string]string{"http": "80", "https": "443", "ftp": "21", "ssh": "22"}[s.Scheme]
+}
+
+// urlMatchScore rates how well an entry URL matches the site, 0 meaning no match
+func urlMatchScore(entryURL string, target *siteURL) int {
+	entryURL = strings.TrimSpace(entryURL)
+	if entryURL == "" {
+		return 0
+	}
+
+	// "*.example.com" matches the subdomains of example.com only
+	wildcard := false
+	if scheme, rest, ok := strings.Cut(entryURL, "://"); ok && strings.HasPrefix(rest, "*.") {
+		entryURL, wildcard = scheme+"://"+rest[2:], true
+	} else if strings.HasPrefix(entryURL, "*.") {
+		entryURL, wildcard = entryURL[2:], true
+	}
+	site, err := parseSiteURL(entryURL)
+	if err != nil {
+		return 0
+	}
+
+	// Ports are compared when either side names one, so http and https URLs of the same
+	// host still match, with a lower score
+	if site.Port != "" || target.Port != "" {
+		sitePort, targetPort := site.effectivePort(), target.effectivePort()
+		if sitePort != "" && targetPort != "" && sitePort != targetPort {
+			return 0
+		}
+	}
+
+	score := 0
+	switch {
+	case site.Host == target.Host && !wildcard:
+		score = urlScoreHost
+	case isSubdomain(target.Host, site.Host):
+		// Nearer parent domains rank higher
+		score = urlScoreSubdomain + strings.Count(site.Host, ".")
+	default:
+		return 0
+	}
+
+	switch {
+	case site.Path == "":
+	case target.Path == site.Path || strings.HasPrefix(target.Path, site.Path+"/"):
+		// Longer matching paths rank higher
+		score += urlScorePath * (1 + strings.Count(site.Path, "/"))
+	default:
+		score -= urlScorePath / 2
+	}
+
+	if site.Scheme != "" && target.Scheme != "" && site.Scheme != target.Scheme {
+		score -= urlScoreScheme
+	}
+	return max(score, 1)
+}
+
+// isSubdomain reports whether host is a subdomain of parent. IP addresses have none.
+func isSubdomain(host, parent string) bool {
+	if net.ParseIP(host) != nil || !strings.Contains(parent, ".") {
+		return false
+	}
+	return strings.HasSuffix(host, "."+parent)
+}
+
+// additionalURLs returns the values of an entry's KP2A_URL fields
+func additionalURLs(entry gokeepasslib.Entry) []string {
+	var urls []string
+	for _, value := range entry.Values {
+		if value.Key == additionalURLKey || strings.HasPrefix(value.Key, additionalURLKey+"_") {
+			if content := strings.TrimSpace(value.Value.Content); content != "" {
+				urls = append(urls, content)
+			}
+		}
+	}
+	return urls
+}
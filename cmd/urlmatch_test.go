@@ -0,0 +1,80 @@
+package cmd
+
+import "testing"
+
+func TestURLMatchScoreMatches(t *testing.T) {
+	tests := []struct {
+		entryURL string
+		target   string
+		match    bool
+	}{
+		{"example.com", "https://example.com", true},
+		{"https://Example.com/", "example.com", true},
+		{"https://example.com:443", "https://example.com", true},
+		{"https://example.com:8443", "https://example.com", false},
+		{"example.com:8443", "https://example.com:8443/login", true},
+		{"http://example.com", "https://example.com", true},
+		{"example.com", "https://mail.example.com", true},
+		{"example.com", "https://notexample.com", false},
+		{"mail.example.com", "https://example.com", false},
+		{"*.example.com", "https://mail.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"*.example.com", "https://example.com", false},
+		{"com", "https://example.com", false},
+		{"10.0.0.1", "https://10.0.0.1", true},
+		{"0.0.1", "https://10.0.0.1", false},
+		{"", "https://example.com", false},
+	}
+	for _, tt := range tests {
+		target, err := parseSiteURL(tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := urlMatchScore(tt.entryURL, target) > 0; got != tt.match {
+			t.Errorf("urlMatchScore(%q, %q) matches = %v, want %v", tt.entryURL, tt.target, got, tt.match)
+		}
+	}
+}
+
+func TestURLMatchScoreRanking(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		// entry URLs from the best to the worst match
+		ranked []string
+	}{
+		{"host before parent domain", "https://grafana.internal.example.com/d/abc", []string{
+			"grafana.internal.example.com",
+			"https://internal.example.com/d/abc",
+			"internal.example.com",
+			"example.com",
+		}},
+		{"longer path first", "https://example.com/admin/users/42", []string{
+			"https://example.com/admin/users",
+			"https://example.com/admin",
+			"https://example.com",
+			"https://example.com/login",
+		}},
+		{"path before scheme", "https://example.com/admin", []string{
+			"ftp://example.com/admin",
+			"https://example.com",
+			"ftp://example.com",
+		}},
+		{"scheme breaks ties", "https://example.com", []string{
+			"https://example.com",
+			"http://example.com",
+		}},
+	}
+	for _, tt := range tests {
+		target, err := parseSiteURL(tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < len(tt.ranked); i++ {
+			better, worse := urlMatchScore(tt.ranked[i-1], target), urlMatchScore(tt.ranked[i], target)
+			if better <= worse {
+				t.Errorf("%s: %q scores %d, not above %q with %d", tt.name, tt.ranked[i-1], better, tt.ranked[i], worse)
+			}
+		}
+	}
+}
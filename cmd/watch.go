@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().Bool("once", false, "Refresh favorites once and exit instead of watching")
+	watchCmd.Flags().Duration("debounce", 2*time.Second, "Wait this long after the last change before refreshing")
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Refresh favorites when the external databases change",
+	Long: `Watch the registered databases and key files, and refresh favorites when they change.
+
+On every change the changed databases are decrypted again, the title, username,
+password and URL cached in each favorite are updated from its source entry, and the
+search index is rebuilt. Favorites whose source entry was deleted, moved to the
+recycle bin, moved to another group or to another registered database are reported.
+Changes to the GoKP database itself reload the list of watched files; the watcher's own
+saves to it are ignored.
+
+Watching uses inotify and is only available on Linux; --once works everywhere.
+
+Examples:
+  gokp watch
+  gokp watch --once`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		once, _ := cmd.Flags().GetBool("once")
+		debounce, _ := cmd.Flags().GetDuration("debounce")
+
+		_, _, gokpKDBX := pathSelection(false)
+		gokpKDBX = filepath.Clean(gokpKDBX)
+
+		secret, err := getGoKPPassword(gokpKDBX)
+		if err != nil {
+			return fmt.Errorf("failed to get GoKP password: %w", err)
+		}
+
+		paths, err := refreshWatchedFavorites(gokpKDBX, secret, nil)
+		if err != nil {
+			return fmt.Errorf("failed to refresh favorites: %w", err)
+		}
+		if once {
+			return nil
+		}
+
+		watcher, err := newFileWatcher()
+		if err != nil {
+			return fmt.Errorf("failed to start watching: %w", err)
+		}
+		defer watcher.close()
+		if err := watcher.watch(append(paths, gokpKDBX)); err != nil {
+			return fmt.Errorf("failed to start watching: %w", err)
+		}
+		fmt.Printf("Watching %d file(s) for changes, press Ctrl+C to stop.\n", len(paths))
+
+		// gokp.kdbx as last refreshed, so the watcher's own saves don't trigger another refresh
+		gokpSeen, _ := os.Stat(gokpKDBX)
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+		// Saving a KDBX usually takes several writes and renames, so changes are collected
+		// until the files have been quiet for the debounce period
+		pending := map[string]bool{}
+		timer := time.NewTimer(debounce)
+		timer.Stop()
+		for {
+			select {
+			case <-sigChan:
+				return nil
+			case err := <-watcher.errors:
+				return fmt.Errorf("watching failed: %w", err)
+			case path := <-watcher.events:
+				pending[path] = true
+				timer.Reset(debounce)
+			case <-timer.C:
+				changed := pending
+				pending = map[string]bool{}
+				if changed[gokpKDBX] && unchangedSince(gokpKDBX, gokpSeen) {
+					delete(changed, gokpKDBX)
+				}
+				if len(changed) == 0 {
+					continue
+				}
+
+				fmt.Printf("\n[%s] Change detected in %s\n", getCurrentTimestamp("datetime"), strings.Join(mapKeys(changed), ", "))
+				if changed[gokpKDBX] {
+					// Registrations may have changed, refresh everything
+					changed = nil
+				}
+				paths, err := refreshWatchedFavorites(gokpKDBX, secret, changed)
+				gokpSeen, _ = os.Stat(gokpKDBX)
+				if err != nil {
+					fmt.Printf("Error: Failed to refresh favorites: %v\n", err)
+					continue
+				}
+				if err := watcher.watch(append(paths, gokpKDBX)); err != nil {
+					fmt.Printf("Error: Failed to update watched files: %v\n", err)
+				}
+			}
+		}
+	},
+}
+
+// refreshWatchedFavorites opens the GoKP database from disk, refreshes the favorites and
+// search indexes of the databases using one of the changed files (all if changed is nil)
+// and saves it if anything changed. It returns the database and key file paths to watch.
+func refreshWatchedFavorites(gokpKDBX, secret string, changed map[string]bool) ([]string, error) {
+	gokpDB, err := openKeepassDB(gokpKDBX, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	databasesGroup := FindRootGroupByName(gokpDB.Content.Root.Groups, databasesGroupName)
+	if databasesGroup == nil {
+		return nil, fmt.Errorf("no databases group found in GoKP database")
+	}
+
+	var paths []string
+	var refresh map[string]bool
+	if changed != nil {
+		refresh = map[string]bool{}
+	}
+	for _, dbEntry := range databasesGroup.Entries {
+		for _, path := range []string{getEntryAttribute(&dbEntry, attrDatabasePath), getEntryAttribute(&dbEntry, attrKeyFilePath)} {
+			if path == "" {
+				continue
+			}
+			path = filepath.Clean(path)
+			paths = append(paths, path)
+			if changed[path] {
+				refresh[dbEntry.GetTitle()] = true
+			}
+		}
+	}
+
+	resolver := newSecretResolver(gokpDB)
+	notices, favoritesChanged := refreshFavorites(resolver, refresh)
+	for _, notice := range notices {
+		fmt.Println(notice)
+	}
+
+	indexChanged := pruneSearchIndexes(gokpDB)
+	for name, db := range resolver.opened {
+		dbPath, err := resolver.databasePath(db)
+		if err != nil {
+			continue
+		}
+		if stored := readSearchIndex(gokpDB, name); stored != nil && !stored.isStale(dbPath) {
+			continue
+		}
+		index, err := buildSearchIndex(dbPath, db)
+		if err != nil {
+			continue
+		}
+		if err := writeSearchIndex(gokpDB, name, index); err == nil {
+			indexChanged = true
+		}
+	}
+
+	if favoritesChanged || indexChanged {
+		if err := saveKeepassDB(gokpDB, gokpKDBX); err != nil {
+			return nil, fmt.Errorf("failed to save GoKP database: %w", err)
+		}
+	}
+	return paths, nil
+}
+
+// unchangedSince reports whether the file at path is still the one seen describes
+func unchangedSince(path string, seen os.FileInfo) bool {
+	info, err := os.Stat(path)
+	return err == nil && seen != nil && os.SameFile(info, seen) && info.ModTime().Equal(seen.ModTime()) && info.Size() == seen.Size()
+}
+
+// refreshFavorites updates the fields cached in each favorite from its source entry, for
+// favorites of the databases in only (all if only is nil). It returns a notice for every
+// favorite that changed or whose source entry is gone, and whether anything was updated.
+func refreshFavorites(resolver *secretResolver, only map[string]bool) ([]string, bool) {
+	favoritesGroup := FindRootGroupByName(resolver.gokpDB.Content.Root.Groups, favoritesGroupName)
+	if favoritesGroup == nil {
+		return nil, false
+	}
+
+	var notices []string
+	changed := false
+	for i := range favoritesGroup.Entries {
+		favorite := &favoritesGroup.Entries[i]
+		source := getEntryAttribute(favorite, attrDatabaseSource)
+		uuid := getEntryAttribute(favorite, attrDatabaseUUID)
+		label := fmt.Sprintf("Favorite %s (%s)", getEntryAttribute(favorite, attrFavoriteIndex), favorite.GetTitle())
+		if only != nil && !only[source] {
+			continue
+		}
+
+		db, err := resolver.database(source)
+		if err != nil {
+			notices = append(notices, fmt.Sprintf("%s: failed to open '%s': %v", label, source, err))
+			continue
+		}
+
+		entry, groupPath, recycled := locateEntry(db, uuid)
+		switch {
+		case entry == nil:
+			if other := findEntryInOtherDatabases(resolver, source, uuid); other != "" {
+				notices = append(notices, fmt.Sprintf("%s: moved from '%s' to database '%s'", label, source, other))
+			} else {
+				notices = append(notices, fmt.Sprintf("%s: deleted from '%s'", label, source))
+			}
+			continue
+		case recycled:
+			notices = append(notices, fmt.Sprintf("%s: moved to the recycle bin in '%s'", label, source))
+			continue
+		}
+
+		newPath := strings.Join(groupPath, "/")
+		if oldPath := getEntryAttribute(favorite, attrGroupPath); oldPath != "" && oldPath != newPath {
+			notices = append(notices, fmt.Sprintf("%s: moved from group '%s' to '%s'", label, oldPath, newPath))
+		}
+
+		// Favorites cache the values with field references expanded
+		resolved := resolvedEntry(db, *entry)
+		updated := false
+		updated = setEntryValue(favorite, "Title", resolved.GetTitle(), false) || updated
+		updated = setEntryValue(favorite, "UserName", getEntryValue(resolved, "UserName"), false) || updated
+		updated = setEntryValue(favorite, "Password", resolved.GetPassword(), true) || updated
+		updated = setEntryValue(favorite, "URL", getEntryValue(resolved, "URL"), false) || updated
+		updated = copyEntryExpiry(favorite, *entry) || updated
+		if favorite.Tags != entry.Tags {
+			favorite.Tags = entry.Tags
+			updated = true
+		}
+		if updated {
+			notices = append(notices, fmt.Sprintf("%s: updated from '%s'", label, source))
+			setEntryValue(favorite, "Last Modified", getCurrentTimestamp("iso"), false)
+		}
+		if setEntryValue(favorite, attrGroupPath, newPath, false) || updated {
+			changed = true
+		}
+	}
+	return notices, changed
+}
+
+// locateEntry finds an entry by hex encoded UUID, returning the names of the groups leading
+// to it and whether it is in the database's recycle bin
+func locateEntry(db *gokeepasslib.Database, uuid string) (*gokeepasslib.Entry, []string, bool) {
+	var recycleBin gokeepasslib.UUID
+	if db.Content.Meta != nil && db.Content.Meta.RecycleBinEnabled.Bool {
+		recycleBin = db.Content.Meta.RecycleBinUUID
+	}
+
+	var search func(groups []gokeepasslib.Group, path []string, recycled bool) (*gokeepasslib.Entry, []string, bool)
+	search = func(groups []gokeepasslib.Group, path []string, recycled bool) (*gokeepasslib.Entry, []string, bool) {
+		for i := range groups {
+			group := &groups[i]
+			groupPath := append(append([]string{}, path...), group.Name)
+			inBin := recycled || (group.UUID.Compare(recycleBin) && recycleBin != gokeepasslib.UUID{})
+			for j := range group.Entries {
+				if strings.EqualFold(fmt.Sprintf("%x", group.Entries[j].UUID), uuid) {
+					return &group.Entries[j], groupPath, inBin
+				}
+			}
+			if entry, entryPath, entryRecycled := search(group.Groups, groupPath, inBin); entry != nil {
+				return entry, entryPath, entryRecycled
+			}
+		}
+		return nil, nil, false
+	}
+	return search(db.Content.Root.Groups, nil, false)
+}
+
+// findEntryInOtherDatabases returns the name of another registered database holding the entry
+func findEntryInOtherDatabases(resolver *secretResolver, source, uuid string) string {
+	databasesGroup := FindRootGroupByName(resolver.gokpDB.Content.Root.Groups, databasesGroupName)
+	if databasesGroup == nil {
+		return ""
+	}
+	for _, dbEntry := range databasesGroup.Entries {
+		name := dbEntry.GetTitle()
+		if name == source {
+			continue
+		}
+		db, err := resolver.database(name)
+		if err != nil {
+			continue
+		}
+		if entry, _, recycled := locateEntry(db, uuid); entry != nil && !recycled {
+			return name
+		}
+	}
+	return ""
+}
+
+// setEntryValue sets a value of an entry, adding it if missing, and reports whether it changed
+func setEntryValue(entry *gokeepasslib.Entry, key, value string, protected bool) bool {
+	for i := range entry.Values {
+		if entry.Values[i].Key == key {
+			if entry.Values[i].Value.Content == value {
+				return false
+			}
+			entry.Values[i].Value.Content = value
+			return true
+		}
+	}
+	if protected {
+		entry.Values = append(entry.Values, mkProtectedValue(key, value))
+	} else {
+		entry.Values = append(entry.Values, mkValue(key, value))
+	}
+	return true
+}
+
+func mapKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
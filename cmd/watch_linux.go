@@ -0,0 +1,100 @@
+//go:build linux
+
+package cmd
+
+import (
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchMask catches in-place writes as well as the write-and-rename saves KeePass clients use
+const watchMask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_ATTRIB
+
+// fileWatcher reports changes to a set of files. It watches their parent folders with
+// inotify, so files that are replaced rather than rewritten keep being watched.
+type fileWatcher struct {
+	fd     int
+	events chan string
+	errors chan error
+
+	mu    sync.Mutex
+	dirs  map[int]string
+	files map[string]bool
+}
+
+func newFileWatcher() (*fileWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	w := &fileWatcher{
+		fd:     fd,
+		events: make(chan string),
+		errors: make(chan error, 1),
+		dirs:   map[int]string{},
+		files:  map[string]bool{},
+	}
+	go w.read()
+	return w, nil
+}
+
+// watch replaces the set of watched files
+func (w *fileWatcher) watch(paths []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.files = map[string]bool{}
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		w.files[path] = true
+		wd, err := unix.InotifyAddWatch(w.fd, filepath.Dir(path), watchMask)
+		if err != nil {
+			return err
+		}
+		w.dirs[wd] = filepath.Dir(path)
+	}
+	return nil
+}
+
+func (w *fileWatcher) close() {
+	unix.Close(w.fd)
+}
+
+func (w *fileWatcher) read() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := unix.Read(w.fd, buf)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			w.errors <- err
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+			offset += unix.SizeofInotifyEvent + int(event.Len)
+
+			name := string(nameBytes)
+			for i := 0; i < len(name); i++ {
+				if name[i] == 0 {
+					name = name[:i]
+					break
+				}
+			}
+
+			w.mu.Lock()
+			path := filepath.Join(w.dirs[int(event.Wd)], name)
+			watched := w.files[path]
+			w.mu.Unlock()
+			if watched {
+				w.events <- path
+			}
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package cmd
+
+import "fmt"
+
+// fileWatcher is only implemented with inotify on Linux
+type fileWatcher struct {
+	events chan string
+	errors chan error
+}
+
+func newFileWatcher() (*fileWatcher, error) {
+	return nil, fmt.Errorf("watching files is only supported on Linux, use `gokp watch --once`")
+}
+
+func (w *fileWatcher) watch(paths []string) error {
+	return nil
+}
+
+func (w *fileWatcher) close() {}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func TestWatchIgnoresOwnGoKPSaves(t *testing.T) {
+	gokpKDBX := setupTestHome(t)
+	entry := newTestEntry("Title", "web", "Password", "new-password")
+	path := registerTestDatabase(t, gokpKDBX, "prod", "prod-password", newTestDatabase("prod-password", newTestGroup("Root", entry)))
+	gokpDB := openTestGoKP(t, gokpKDBX)
+	cached := entry
+	cached.Values = []gokeepasslib.ValueData{mkValue("Title", "web"), mkProtectedValue("Password", "old-password")}
+	if err := addFavoriteEntryToGoKP(gokpDB, SearchResult{Entry: cached, DatabaseName: "prod", DatabasePath: path}); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveKeepassDB(gokpDB, gokpKDBX); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.Stat(gokpKDBX)
+
+	// The refresh updates the favorite, saving gokp.kdbx
+	if _, err := refreshWatchedFavorites(gokpKDBX, testGoKPPassword, nil); err != nil {
+		t.Fatal(err)
+	}
+	if unchangedSince(gokpKDBX, before) {
+		t.Fatal("refreshing the outdated favorite did not save gokp.kdbx")
+	}
+	seen, _ := os.Stat(gokpKDBX)
+
+	// Refreshing again after the watcher's own save finds nothing to save
+	if _, err := refreshWatchedFavorites(gokpKDBX, testGoKPPassword, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !unchangedSince(gokpKDBX, seen) {
+		t.Error("a refresh without changes rewrote gokp.kdbx")
+	}
+
+	// A save by someone else is noticed
+	if err := saveKeepassDB(openTestGoKP(t, gokpKDBX), gokpKDBX); err != nil {
+		t.Fatal(err)
+	}
+	if unchangedSince(gokpKDBX, seen) {
+		t.Error("a save by another process was taken for the watcher's own")
+	}
+}